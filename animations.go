@@ -176,6 +176,21 @@ func (a *Animation) DrawAnimation(screen *ebiten.Image, x, y int, scale ...float
 	screen.DrawImage(a.frames[a.currentFrame], op)
 }
 
+func (a *Animation) DrawAnimationCamera(screen *ebiten.Image, camera *Camera, x, y float64, scale ...float64) {
+	if len(a.frames) == 0 {
+		return
+	}
+	s := 1.0
+	if len(scale) > 0 {
+		s = scale[0]
+	}
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Scale(s, s)
+	op.GeoM.Translate(x, y)
+	op.GeoM.Concat(camera.Matrix())
+	camera.Image(screen).DrawImage(a.frames[a.currentFrame], op)
+}
+
 func (a *Animation) Reset() {
 	a.currentFrame = 0
 	a.elapsedTime = 0
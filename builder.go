@@ -0,0 +1,208 @@
+package gobonsai
+
+import (
+	"fmt"
+	"image"
+)
+
+type TileMapBuilder struct {
+	m            Map
+	tilesets     []Tileset
+	layers       map[string]int
+	nextGID      int
+	nextObjectID int
+	err          error
+}
+
+func NewTileMapBuilder(width, height, tileWidth, tileHeight int) *TileMapBuilder {
+	return &TileMapBuilder{
+		m: Map{
+			Width:       width,
+			Height:      height,
+			TileWidth:   tileWidth,
+			TileHeight:  tileHeight,
+			Orientation: "orthogonal",
+			RenderOrder: "right-down",
+		},
+		layers:       make(map[string]int),
+		nextGID:      1,
+		nextObjectID: 1,
+	}
+}
+
+func (b *TileMapBuilder) fail(err error) {
+	if b.err == nil {
+		b.err = err
+	}
+}
+
+func (b *TileMapBuilder) layer(name string) *Layer {
+	idx, ok := b.layers[name]
+	if !ok {
+		b.fail(fmt.Errorf("layer %s not found", name))
+		return nil
+	}
+	return &b.m.Layers[idx]
+}
+
+func (b *TileMapBuilder) SetOrientation(orientation string) *TileMapBuilder {
+	b.m.Orientation = orientation
+	return b
+}
+
+func (b *TileMapBuilder) SetProperty(name string, value interface{}) *TileMapBuilder {
+	b.m.RawProps = setPropertyList(b.m.RawProps, name, value)
+	return b
+}
+
+func (b *TileMapBuilder) AddTileset(ts Tileset) int {
+	if ts.FirstGID == 0 {
+		ts.FirstGID = b.nextGID
+	}
+	count := ts.TileCount
+	if count == 0 && ts.TileWidth > 0 && ts.TileHeight > 0 {
+		columns := ts.Columns
+		if columns == 0 {
+			columns = (ts.ImageWidth - 2*ts.Margin + ts.Spacing) / (ts.TileWidth + ts.Spacing)
+		}
+		count = columns * ((ts.ImageHeight - 2*ts.Margin + ts.Spacing) / (ts.TileHeight + ts.Spacing))
+	}
+	for _, t := range ts.Tiles {
+		count = max(count, t.ID+1)
+	}
+	if count <= 0 {
+		b.fail(fmt.Errorf("tileset %s needs tilecount or image size", ts.Name))
+	}
+	b.nextGID = max(b.nextGID, ts.FirstGID+count)
+	b.tilesets = append(b.tilesets, ts)
+	return ts.FirstGID
+}
+
+func (b *TileMapBuilder) TileGID(tileset string, id int) int {
+	for _, ts := range b.tilesets {
+		if ts.Name == tileset {
+			return ts.FirstGID + id
+		}
+	}
+	b.fail(fmt.Errorf("tileset %s not found", tileset))
+	return 0
+}
+
+func (b *TileMapBuilder) addLayer(l Layer) *TileMapBuilder {
+	if _, ok := b.layers[l.Name]; ok {
+		b.fail(fmt.Errorf("layer %s already exists", l.Name))
+		return b
+	}
+	l.Visible, l.Opacity, l.ParallaxX, l.ParallaxY = true, 1, 1, 1
+	b.layers[l.Name] = len(b.m.Layers)
+	b.m.Layers = append(b.m.Layers, l)
+	return b
+}
+
+func (b *TileMapBuilder) AddTileLayer(name string) *TileMapBuilder {
+	return b.addLayer(Layer{
+		Name:   name,
+		Type:   "tilelayer",
+		Width:  b.m.Width,
+		Height: b.m.Height,
+		Data:   make([]int, b.m.Width*b.m.Height),
+	})
+}
+
+func (b *TileMapBuilder) AddObjectLayer(name string) *TileMapBuilder {
+	return b.addLayer(Layer{Name: name, Type: "objectgroup"})
+}
+
+func (b *TileMapBuilder) SetLayerClass(layer, class string) *TileMapBuilder {
+	if l := b.layer(layer); l != nil {
+		l.Class = class
+	}
+	return b
+}
+
+func (b *TileMapBuilder) SetLayerProperty(layer, name string, value interface{}) *TileMapBuilder {
+	if l := b.layer(layer); l != nil {
+		l.RawProps = setPropertyList(l.RawProps, name, value)
+	}
+	return b
+}
+
+func (b *TileMapBuilder) SetTile(layer string, x, y, gid int) *TileMapBuilder {
+	if l := b.layer(layer); l != nil && !l.setGID(x, y, gid) {
+		b.fail(fmt.Errorf("tile %d,%d outside layer %s", x, y, layer))
+	}
+	return b
+}
+
+func (b *TileMapBuilder) FillRect(layer string, r image.Rectangle, gid int) *TileMapBuilder {
+	r = r.Intersect(image.Rect(0, 0, b.m.Width, b.m.Height))
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			b.SetTile(layer, x, y, gid)
+		}
+	}
+	return b
+}
+
+func (b *TileMapBuilder) AddObject(layer string, obj Object) int {
+	l := b.layer(layer)
+	if l == nil {
+		return 0
+	}
+	if l.Type != "objectgroup" {
+		b.fail(fmt.Errorf("layer %s is not an object layer", layer))
+		return 0
+	}
+	if obj.ID == 0 {
+		obj.ID = b.nextObjectID
+	}
+	b.nextObjectID = max(b.nextObjectID, obj.ID+1)
+	obj.Visible = true
+	l.Objects = append(l.Objects, obj)
+	return obj.ID
+}
+
+func (b *TileMapBuilder) ApplyGrid(layer string, g *GenGrid, floorGID, wallGID int) *TileMapBuilder {
+	for y := 0; y < g.Height; y++ {
+		for x := 0; x < g.Width; x++ {
+			if g.IsFloor(x, y) {
+				b.SetTile(layer, x, y, floorGID)
+			} else {
+				b.SetTile(layer, x, y, wallGID)
+			}
+		}
+	}
+	return b
+}
+
+func (tmm *TilemapsManager) AddBuiltTilemap(name string, b *TileMapBuilder) error {
+	if b.err != nil {
+		return fmt.Errorf("failed to build tilemap %s: %w", name, b.err)
+	}
+	m := b.m
+	m.Layers = append([]Layer(nil), b.m.Layers...)
+	for i := range m.Layers {
+		m.Layers[i].Data = append([]int(nil), m.Layers[i].Data...)
+		m.Layers[i].Objects = append([]Object(nil), m.Layers[i].Objects...)
+	}
+	tileMap := newTileMap(name, &m)
+	for _, ts := range b.tilesets {
+		tileMap.Tilesets = append(tileMap.Tilesets, &ts)
+		if err := tmm.loadTilesetImages(tileMap, &ts); err != nil {
+			return fmt.Errorf("failed to build tilemap %s: %w", name, err)
+		}
+	}
+	tileMap.indexWangSets()
+	if err := tmm.loadLayerImages(tileMap, tileMap.Map.Layers); err != nil {
+		return fmt.Errorf("failed to load image layers: %w", err)
+	}
+	if err := tmm.resolveObjects(tileMap, "."); err != nil {
+		return fmt.Errorf("failed to resolve objects: %w", err)
+	}
+
+	tmm.mu.Lock()
+	defer tmm.mu.Unlock()
+	tmm.tileMaps[name] = tileMap
+	tmm.logger.Info("Tilemap built:", name)
+	return nil
+}
@@ -0,0 +1,249 @@
+package gobonsai
+
+import (
+	"image"
+	"math"
+	"math/rand"
+	"sync"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+type Camera struct {
+	X              float64
+	Y              float64
+	Zoom           float64
+	Rotation       float64
+	Viewport       image.Rectangle
+	Target         Entity
+	Smoothing      float64
+	DeadzoneWidth  float64
+	DeadzoneHeight float64
+	Bounds         image.Rectangle
+	shakeIntensity float64
+	shakeDuration  float64
+	shakeElapsed   float64
+	shakeX         float64
+	shakeY         float64
+}
+
+func NewCamera(viewport image.Rectangle) *Camera {
+	return &Camera{Zoom: 1, Viewport: viewport}
+}
+
+func (c *Camera) Follow(e Entity, smoothing float64) {
+	c.Target = e
+	c.Smoothing = smoothing
+}
+
+func (c *Camera) SetDeadzone(width, height float64) {
+	c.DeadzoneWidth, c.DeadzoneHeight = width, height
+}
+
+func (c *Camera) SetBounds(x, y, width, height float64) {
+	c.Bounds = image.Rect(int(x), int(y), int(x+width), int(y+height))
+}
+
+func (c *Camera) ClampToTilemap(tmm *TilemapsManager) {
+	x, y, w, h := tmm.GetBounds()
+	c.SetBounds(x, y, w, h)
+}
+
+func (c *Camera) Shake(intensity, duration float64) {
+	c.shakeIntensity = intensity
+	c.shakeDuration = duration
+	c.shakeElapsed = 0
+}
+
+func (c *Camera) LookAt(x, y float64) {
+	c.X, c.Y = x, y
+	c.clamp()
+}
+
+func (c *Camera) Update(deltaTime float64) {
+	if c.Target != 0 {
+		if pos, ok := c.Target.GetComponent("position").(*PositionComponent); ok {
+			tx, ty := pos.X, pos.Y
+			if size, ok := c.Target.GetComponent("size").(*SizeComponent); ok {
+				tx += size.Width / 2
+				ty += size.Height / 2
+			}
+			tx = c.X + deadzoneDelta(tx-c.X, c.DeadzoneWidth/2)
+			ty = c.Y + deadzoneDelta(ty-c.Y, c.DeadzoneHeight/2)
+			if c.Smoothing > 0 {
+				t := 1 - math.Exp(-c.Smoothing*deltaTime)
+				c.X += (tx - c.X) * t
+				c.Y += (ty - c.Y) * t
+			} else {
+				c.X, c.Y = tx, ty
+			}
+		}
+	}
+	c.clamp()
+
+	c.shakeX, c.shakeY = 0, 0
+	if c.shakeElapsed < c.shakeDuration {
+		c.shakeElapsed += deltaTime
+		strength := c.shakeIntensity * math.Max(0, 1-c.shakeElapsed/c.shakeDuration)
+		c.shakeX = (rand.Float64()*2 - 1) * strength
+		c.shakeY = (rand.Float64()*2 - 1) * strength
+	}
+}
+
+func deadzoneDelta(d, half float64) float64 {
+	switch {
+	case d > half:
+		return d - half
+	case d < -half:
+		return d + half
+	default:
+		return 0
+	}
+}
+
+func (c *Camera) clamp() {
+	if c.Bounds.Empty() {
+		return
+	}
+	viewport := c.viewport()
+	halfW := float64(viewport.Dx()) / c.zoom() / 2
+	halfH := float64(viewport.Dy()) / c.zoom() / 2
+	c.X = clampRange(c.X, float64(c.Bounds.Min.X)+halfW, float64(c.Bounds.Max.X)-halfW)
+	c.Y = clampRange(c.Y, float64(c.Bounds.Min.Y)+halfH, float64(c.Bounds.Max.Y)-halfH)
+}
+
+func clampRange(v, lo, hi float64) float64 {
+	if lo > hi {
+		return (lo + hi) / 2
+	}
+	return math.Max(lo, math.Min(hi, v))
+}
+
+func (c *Camera) zoom() float64 {
+	if c.Zoom <= 0 {
+		return 1
+	}
+	return c.Zoom
+}
+
+func (c *Camera) viewport() image.Rectangle {
+	if c.Viewport.Empty() {
+		return image.Rect(0, 0, gwidth, gheight)
+	}
+	return c.Viewport
+}
+
+func (c *Camera) Matrix() ebiten.GeoM {
+	viewport := c.viewport()
+	var m ebiten.GeoM
+	m.Translate(-(c.X + c.shakeX), -(c.Y + c.shakeY))
+	m.Rotate(-c.Rotation)
+	m.Scale(c.zoom(), c.zoom())
+	m.Translate(float64(viewport.Min.X)+float64(viewport.Dx())/2, float64(viewport.Min.Y)+float64(viewport.Dy())/2)
+	return m
+}
+
+func (c *Camera) WorldToScreen(x, y float64) (float64, float64) {
+	m := c.Matrix()
+	return m.Apply(x, y)
+}
+
+func (c *Camera) ScreenToWorld(x, y float64) (float64, float64) {
+	m := c.Matrix()
+	m.Invert()
+	return m.Apply(x, y)
+}
+
+func (c *Camera) VisibleRect() (float64, float64, float64, float64) {
+	return visibleRect(c.Matrix(), c.viewport())
+}
+
+func visibleRect(view ebiten.GeoM, screen image.Rectangle) (float64, float64, float64, float64) {
+	if !view.IsInvertible() {
+		return 0, 0, 0, 0
+	}
+	view.Invert()
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, p := range []image.Point{screen.Min, {screen.Max.X, screen.Min.Y}, {screen.Min.X, screen.Max.Y}, screen.Max} {
+		x, y := view.Apply(float64(p.X), float64(p.Y))
+		minX, minY = math.Min(minX, x), math.Min(minY, y)
+		maxX, maxY = math.Max(maxX, x), math.Max(maxY, y)
+	}
+	return minX, minY, maxX - minX, maxY - minY
+}
+
+func (c *Camera) Image(screen *ebiten.Image) *ebiten.Image {
+	if c.Viewport.Empty() {
+		return screen
+	}
+	return screen.SubImage(c.Viewport.Intersect(screen.Bounds())).(*ebiten.Image)
+}
+
+type CamerasManager struct {
+	cameras map[string]*Camera
+	order   []string
+	logger  *Logger
+	mu      sync.Mutex
+}
+
+func NewCamerasManager() *CamerasManager {
+	return &CamerasManager{
+		cameras: make(map[string]*Camera),
+		logger:  NewLogger("bonsai:camera"),
+	}
+}
+
+func (cm *CamerasManager) AddCamera(name string, camera *Camera) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	if _, exists := cm.cameras[name]; !exists {
+		cm.order = append(cm.order, name)
+	}
+	cm.cameras[name] = camera
+	cm.logger.Debug("Camera added:", name)
+}
+
+func (cm *CamerasManager) GetCamera(name string) *Camera {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	return cm.cameras[name]
+}
+
+func (cm *CamerasManager) RemoveCamera(name string) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	if _, exists := cm.cameras[name]; !exists {
+		return
+	}
+	delete(cm.cameras, name)
+	for i, n := range cm.order {
+		if n == name {
+			cm.order = append(cm.order[:i], cm.order[i+1:]...)
+			break
+		}
+	}
+	cm.logger.Debug("Camera removed:", name)
+}
+
+func (cm *CamerasManager) GetCameras() []*Camera {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	cameras := make([]*Camera, 0, len(cm.order))
+	for _, name := range cm.order {
+		cameras = append(cameras, cm.cameras[name])
+	}
+	return cameras
+}
+
+func (cm *CamerasManager) UpdateCameras(deltaTime float64) {
+	for _, camera := range cm.GetCameras() {
+		camera.Update(deltaTime)
+	}
+}
+
+func (cm *CamerasManager) DrawCameras(screen *ebiten.Image, draw func(view *ebiten.Image, camera *Camera)) {
+	for _, camera := range cm.GetCameras() {
+		draw(camera.Image(screen), camera)
+	}
+}
@@ -1,31 +1,144 @@
 package gobonsai
 
 import (
-	"fmt"
+	"cmp"
 	"image/color"
+	"math"
+	"path"
+	"slices"
 	"sync"
 
 	"github.com/hajimehoshi/ebiten/v2"
-	"github.com/hajimehoshi/ebiten/v2/vector"
 )
 
+type Manifold struct {
+	EntityA Entity
+	EntityB Entity
+	Normal  Vector
+	Depth   float64
+}
+
+func (m Manifold) Penetration() Vector {
+	return m.Normal.Scale(m.Depth)
+}
+
+func (m Manifold) Swap() Manifold {
+	return Manifold{EntityA: m.EntityB, EntityB: m.EntityA, Normal: m.Normal.Scale(-1), Depth: m.Depth}
+}
+
 type CollisionHandler struct {
-	Enter func(entityA, entityB Entity)
-	Stay  func(entityA, entityB Entity)
-	Leave func(entityA, entityB Entity)
+	Filter func(other Entity) bool
+	Enter  func(m Manifold)
+	Stay   func(m Manifold)
+	Leave  func(m Manifold)
+}
+
+type collisionEvent int
+
+const (
+	collisionEnter collisionEvent = iota
+	collisionStay
+	collisionLeave
+)
+
+type collisionResolver struct {
+	handler CollisionHandler
+	swap    bool
+}
+
+func (r collisionResolver) fire(m Manifold, event collisionEvent) {
+	if r.swap {
+		m = m.Swap()
+	}
+	if r.handler.Filter != nil && !r.handler.Filter(m.EntityB) {
+		return
+	}
+	var fn func(Manifold)
+	switch event {
+	case collisionEnter:
+		fn = r.handler.Enter
+	case collisionStay:
+		fn = r.handler.Stay
+	case collisionLeave:
+		fn = r.handler.Leave
+	}
+	if fn != nil {
+		fn(m)
+	}
+}
+
+func FilterGroups(groups ...string) func(Entity) bool {
+	return func(e Entity) bool {
+		c, ok := e.GetComponent("collider").(*ColliderComponent)
+		if !ok {
+			return false
+		}
+		for _, g := range groups {
+			if matchGroup(g, c.Group) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func matchGroup(pattern, group string) bool {
+	if pattern == group || pattern == "*" {
+		return true
+	}
+	ok, err := path.Match(pattern, group)
+	return err == nil && ok
+}
+
+type collisionPair uint64
+
+func makeCollisionPair(e1, e2 Entity) collisionPair {
+	if e1 > e2 {
+		e1, e2 = e2, e1
+	}
+	return collisionPair(uint64(e1)<<32 | uint64(e2))
+}
+
+func (p collisionPair) entities() (Entity, Entity) {
+	return Entity(p >> 32), Entity(p & 0xffffffff)
+}
+
+type groupPair struct {
+	a, b string
+}
+
+type colliderEntry struct {
+	entity     Entity
+	collider   *ColliderComponent
+	handler    CollisionHandler
+	hasHandler bool
+	shapes     []convexShape
+	min        Vector
+	max        Vector
 }
 
 type CollisionManager struct {
 	handlers           map[string]map[string]CollisionHandler
-	previousCollisions sync.Map
+	groupResolvers     map[groupPair][]collisionResolver
+	previousCollisions map[collisionPair]Manifold
+	currentCollisions  map[collisionPair]Manifold
+	contacts           []Manifold
+	entities           []Entity
+	entries            []colliderEntry
+	shapes             []convexShape
+	points             []Vector
+	resolvers          []collisionResolver
 	ecs                *ECSManager
 	mu                 sync.RWMutex
 }
 
 func NewCollisionManager(ecs *ECSManager) *CollisionManager {
 	return &CollisionManager{
-		handlers: make(map[string]map[string]CollisionHandler),
-		ecs:      ecs,
+		handlers:           make(map[string]map[string]CollisionHandler),
+		groupResolvers:     make(map[groupPair][]collisionResolver),
+		previousCollisions: make(map[collisionPair]Manifold),
+		currentCollisions:  make(map[collisionPair]Manifold),
+		ecs:                ecs,
 	}
 }
 
@@ -38,122 +151,247 @@ func (cm *CollisionManager) AddResolve(groupA, groupB string, handler CollisionH
 	}
 
 	cm.handlers[groupA][groupB] = handler
+	clear(cm.groupResolvers)
 }
 
-func (cm *CollisionManager) Update() {
-	currentCollisions := sync.Map{}
-	processed := sync.Map{}
+func (cm *CollisionManager) AddEntityResolve(e Entity, handler CollisionHandler) {
+	e.AddComponent("collision", handler)
+}
 
-	entities := cm.ecs.GetEntitiesWithComponents("collider", "position", "size")
+func (cm *CollisionManager) AddTrigger(e Entity, handler CollisionHandler) {
+	if c, ok := e.GetComponent("collider").(*ColliderComponent); ok {
+		c.Sensor = true
+	}
+	cm.AddEntityResolve(e, handler)
+}
 
-	for _, e1 := range entities {
-		c1, p1, s1 := cm.getComponents(e1)
-		if c1 == nil || p1 == nil || s1 == nil {
-			continue
-		}
+func (cm *CollisionManager) Update() {
+	cm.buildEntries()
 
-		for _, e2 := range entities {
-			if e1 == e2 {
-				continue
+	for i := range cm.entries {
+		for j := i + 1; j < len(cm.entries); j++ {
+			a, b := &cm.entries[i], &cm.entries[j]
+			if b.min.X >= a.max.X {
+				break
 			}
-
-			c2, p2, s2 := cm.getComponents(e2)
-			if c2 == nil || p2 == nil || s2 == nil {
+			if b.min.Y >= a.max.Y || b.max.Y <= a.min.Y {
 				continue
 			}
+			if b.entity < a.entity {
+				a, b = b, a
+			}
 
-			handler, ok := cm.getHandler(c1.Group, c2.Group)
+			normal, depth, ok := collideEntries(a, b)
 			if !ok {
 				continue
 			}
 
-			key := cm.collisionKey(e1, e2)
-			if _, alreadyProcessed := processed.Load(key); alreadyProcessed {
+			key := makeCollisionPair(a.entity, b.entity)
+			m := Manifold{EntityA: a.entity, EntityB: b.entity, Normal: normal, Depth: depth}
+			cm.currentCollisions[key] = m
+
+			cm.resolvers = cm.appendResolvers(cm.resolvers[:0], a, b)
+			if len(cm.resolvers) == 0 {
 				continue
 			}
-			processed.Store(key, true)
-
-			if cm.checkCollision(p1, c1, p2, c2) {
-				currentCollisions.Store(key, true)
-
-				_, wasColliding := cm.previousCollisions.Load(key)
-
-				if !wasColliding {
-					if handler.Enter != nil {
-						handler.Enter(e1, e2)
-					}
-					cm.previousCollisions.Store(key, true)
-				} else {
-					if handler.Stay != nil {
-						handler.Stay(e1, e2)
-					}
-				}
+
+			event := collisionStay
+			if _, wasColliding := cm.previousCollisions[key]; !wasColliding {
+				event = collisionEnter
+			}
+			for _, r := range cm.resolvers {
+				r.fire(m, event)
 			}
 		}
 	}
 
-	cm.previousCollisions.Range(func(k, v interface{}) bool {
-		if _, stillColliding := currentCollisions.Load(k); !stillColliding {
-			e1, e2 := cm.parseCollisionKey(k.(string))
-
-			c1, _, _ := cm.getComponents(e1)
-			c2, _, _ := cm.getComponents(e2)
-			if c1 == nil || c2 == nil {
-				cm.previousCollisions.Delete(k)
-				return true
-			}
+	for key := range cm.previousCollisions {
+		if _, stillColliding := cm.currentCollisions[key]; stillColliding {
+			continue
+		}
+		e1, e2 := key.entities()
+		a, okA := cm.getEntry(e1)
+		b, okB := cm.getEntry(e2)
+		if !okA || !okB {
+			continue
+		}
+		cm.resolvers = cm.appendResolvers(cm.resolvers[:0], &a, &b)
+		for _, r := range cm.resolvers {
+			r.fire(Manifold{EntityA: e1, EntityB: e2}, collisionLeave)
+		}
+	}
 
-			handler, ok := cm.getHandler(c1.Group, c2.Group)
-			if ok && handler.Leave != nil {
-				handler.Leave(e1, e2)
-			}
+	cm.previousCollisions, cm.currentCollisions = cm.currentCollisions, cm.previousCollisions
+	clear(cm.currentCollisions)
+	cm.buildContacts()
+}
 
-			cm.previousCollisions.Delete(k)
+func (cm *CollisionManager) buildContacts() {
+	cm.contacts = cm.contacts[:0]
+	for _, m := range cm.previousCollisions {
+		cm.contacts = append(cm.contacts, m, m.Swap())
+	}
+	slices.SortFunc(cm.contacts, func(a, b Manifold) int {
+		if c := cmp.Compare(a.EntityA, b.EntityA); c != 0 {
+			return c
 		}
-		return true
+		return cmp.Compare(a.EntityB, b.EntityB)
 	})
 }
 
-func (cm *CollisionManager) checkCollision(p1 *PositionComponent, c1 *ColliderComponent,
-	p2 *PositionComponent, c2 *ColliderComponent) bool {
-
-	var x1, y1, w1, h1 float64
-	var x2, y2, w2, h2 float64
+func (cm *CollisionManager) GetContacts(e Entity) []Manifold {
+	start, _ := slices.BinarySearchFunc(cm.contacts, e, func(m Manifold, e Entity) int {
+		return cmp.Compare(m.EntityA, e)
+	})
+	end := start
+	for end < len(cm.contacts) && cm.contacts[end].EntityA == e {
+		end++
+	}
+	contacts := make([]Manifold, end-start)
+	copy(contacts, cm.contacts[start:end])
+	return contacts
+}
 
-	if c1.CrossShape {
-		hx1, hy1, hw1, hh1 := p1.X+c1.OffsetX-c1.HorizWidth/2, p1.Y+c1.OffsetY-c1.HorizHeight/2, c1.HorizWidth, c1.HorizHeight
-		vx1, vy1, vw1, vh1 := p1.X+c1.OffsetX-c1.VertWidth/2, p1.Y+c1.OffsetY-c1.VertHeight/2, c1.VertWidth, c1.VertHeight
+func (cm *CollisionManager) IsColliding(e1, e2 Entity) bool {
+	_, ok := cm.previousCollisions[makeCollisionPair(e1, e2)]
+	return ok
+}
 
-		if cm.rectOverlap(hx1, hy1, hw1, hh1, p2.X+c2.OffsetX, p2.Y+c2.OffsetY, c2.Width, c2.Height) ||
-			cm.rectOverlap(vx1, vy1, vw1, vh1, p2.X+c2.OffsetX, p2.Y+c2.OffsetY, c2.Width, c2.Height) {
+func (cm *CollisionManager) Overlaps(e Entity, group string) bool {
+	for _, m := range cm.GetContacts(e) {
+		if c, ok := m.EntityB.GetComponent("collider").(*ColliderComponent); ok && matchGroup(group, c.Group) {
 			return true
 		}
-		return false
-	} else {
-		x1, y1, w1, h1 = p1.X+c1.OffsetX, p1.Y+c1.OffsetY, c1.Width, c1.Height
 	}
+	return false
+}
 
-	if c2.CrossShape {
-		hx2, hy2, hw2, hh2 := p2.X+c2.OffsetX-c2.HorizWidth/2, p2.Y+c2.OffsetY-c2.HorizHeight/2, c2.HorizWidth, c2.HorizHeight
-		vx2, vy2, vw2, vh2 := p2.X+c2.OffsetX-c2.VertWidth/2, p2.Y+c2.OffsetY-c2.VertHeight/2, c2.VertWidth, c2.VertHeight
+func (cm *CollisionManager) QueryArea(x, y, w, h float64, groups ...string) []Entity {
+	area := convexShape{points: []Vector{{x, y}, {x + w, y}, {x + w, y + h}, {x, y + h}}}
+	return cm.query(Vector{x, y}, Vector{x + w, y + h}, func(s convexShape) bool {
+		_, _, ok := collideShapes(area, s)
+		return ok
+	}, groups)
+}
 
-		if cm.rectOverlap(hx2, hy2, hw2, hh2, p1.X+c1.OffsetX, p1.Y+c1.OffsetY, c1.Width, c1.Height) ||
-			cm.rectOverlap(vx2, vy2, vw2, vh2, p1.X+c1.OffsetX, p1.Y+c1.OffsetY, c1.Width, c1.Height) {
-			return true
+func (cm *CollisionManager) QueryPoint(x, y float64, groups ...string) []Entity {
+	pt := Vector{x, y}
+	return cm.query(pt, pt, func(s convexShape) bool {
+		return s.contains(pt)
+	}, groups)
+}
+
+func (cm *CollisionManager) query(lo, hi Vector, test func(convexShape) bool, groups []string) []Entity {
+	var result []Entity
+	for i := range cm.entries {
+		entry := &cm.entries[i]
+		if entry.min.X > hi.X {
+			break
+		}
+		if entry.max.X < lo.X || entry.min.Y > hi.Y || entry.max.Y < lo.Y {
+			continue
+		}
+		if _, alive := cm.ecs.entities.Load(entry.entity); !alive {
+			continue
+		}
+		if len(groups) > 0 && !slices.ContainsFunc(groups, func(g string) bool {
+			return matchGroup(g, entry.collider.Group)
+		}) {
+			continue
+		}
+		if slices.ContainsFunc(entry.shapes, test) {
+			result = append(result, entry.entity)
 		}
-		return false
-	} else {
-		x2, y2, w2, h2 = p2.X+c2.OffsetX, p2.Y+c2.OffsetY, c2.Width, c2.Height
 	}
+	return cm.ecs.SortEntities(result)
+}
+
+func (cm *CollisionManager) buildEntries() {
+	cm.entities = cm.ecs.AppendEntitiesWithComponents(cm.entities[:0], "collider", "position", "size")
+	cm.entries = cm.entries[:0]
+	cm.shapes = cm.shapes[:0]
+	cm.points = cm.points[:0]
 
-	return cm.rectOverlap(x1, y1, w1, h1, x2, y2, w2, h2)
+	for _, e := range cm.entities {
+		c, p, s := cm.getComponents(e)
+		if c == nil || p == nil || s == nil {
+			continue
+		}
+		start := len(cm.shapes)
+		cm.shapes, cm.points = appendColliderShapes(cm.shapes, cm.points, p, c)
+		entry := colliderEntry{
+			entity:   e,
+			collider: c,
+			shapes:   cm.shapes[start:len(cm.shapes):len(cm.shapes)],
+			min:      Vector{math.Inf(1), math.Inf(1)},
+			max:      Vector{math.Inf(-1), math.Inf(-1)},
+		}
+		for _, shape := range entry.shapes {
+			lo, hi := shape.bounds()
+			entry.min = Vector{math.Min(entry.min.X, lo.X), math.Min(entry.min.Y, lo.Y)}
+			entry.max = Vector{math.Max(entry.max.X, hi.X), math.Max(entry.max.Y, hi.Y)}
+		}
+		entry.handler, entry.hasHandler = e.GetComponent("collision").(CollisionHandler)
+		cm.entries = append(cm.entries, entry)
+	}
+
+	slices.SortFunc(cm.entries, func(a, b colliderEntry) int {
+		return cmp.Compare(a.min.X, b.min.X)
+	})
 }
 
-func (cm *CollisionManager) rectOverlap(x1, y1, w1, h1, x2, y2, w2, h2 float64) bool {
-	return x1 < x2+w2 && x1+w1 > x2 && y1 < y2+h2 && y1+h1 > y2
+func (cm *CollisionManager) getEntry(e Entity) (colliderEntry, bool) {
+	c, p, s := cm.getComponents(e)
+	if c == nil || p == nil || s == nil {
+		return colliderEntry{}, false
+	}
+	entry := colliderEntry{entity: e, collider: c}
+	entry.handler, entry.hasHandler = e.GetComponent("collision").(CollisionHandler)
+	return entry, true
+}
+
+func collideEntries(a, b *colliderEntry) (Vector, float64, bool) {
+	var normal Vector
+	var depth float64
+	found := false
+	for _, sa := range a.shapes {
+		for _, sb := range b.shapes {
+			n, d, ok := collideShapes(sa, sb)
+			if ok && (!found || d > depth) {
+				normal, depth, found = n, d, true
+			}
+		}
+	}
+	return normal, depth, found
+}
+
+func (cm *CollisionManager) collide(p1 *PositionComponent, c1 *ColliderComponent,
+	p2 *PositionComponent, c2 *ColliderComponent) (Vector, float64, bool) {
+
+	a := colliderEntry{shapes: colliderShapes(p1, c1)}
+	b := colliderEntry{shapes: colliderShapes(p2, c2)}
+	return collideEntries(&a, &b)
+}
+
+func (cm *CollisionManager) GetManifold(e1, e2 Entity) (Manifold, bool) {
+	c1, p1, _ := cm.getComponents(e1)
+	c2, p2, _ := cm.getComponents(e2)
+	if c1 == nil || c2 == nil {
+		return Manifold{}, false
+	}
+	normal, depth, ok := cm.collide(p1, c1, p2, c2)
+	return Manifold{EntityA: e1, EntityB: e2, Normal: normal, Depth: depth}, ok
 }
 
 func (cm *CollisionManager) Draw(screen *ebiten.Image) {
+	cm.drawColliders(screen, ebiten.GeoM{})
+}
+
+func (cm *CollisionManager) DrawCamera(screen *ebiten.Image, camera *Camera) {
+	cm.drawColliders(camera.Image(screen), camera.Matrix())
+}
+
+func (cm *CollisionManager) drawColliders(screen *ebiten.Image, view ebiten.GeoM) {
 	entities := cm.ecs.GetEntitiesWithComponents("collider", "position")
 
 	for _, e := range entities {
@@ -162,34 +400,56 @@ func (cm *CollisionManager) Draw(screen *ebiten.Image) {
 			continue
 		}
 
-		var x, y, w, h float64
+		col := color.RGBA{0, 255, 0, 120}
 		if c.CrossShape {
-			hx, hy, hw, hh := p.X+c.OffsetX-c.HorizWidth/2, p.Y+c.OffsetY-c.HorizHeight/2, c.HorizWidth, c.HorizHeight
-			vx, vy, vw, vh := p.X+c.OffsetX-c.VertWidth/2, p.Y+c.OffsetY-c.VertHeight/2, c.VertWidth, c.VertHeight
-
-			cm.drawRect(screen, hx, hy, hw, hh, color.RGBA{0, 0, 255, 120})
-			cm.drawRect(screen, vx, vy, vw, vh, color.RGBA{0, 0, 255, 120})
-		} else {
-			x, y, w, h = p.X+c.OffsetX, p.Y+c.OffsetY, c.Width, c.Height
-			cm.drawRect(screen, x, y, w, h, color.RGBA{255, 0, 0, 120})
+			col = color.RGBA{0, 0, 255, 120}
+		} else if c.Shape == ShapeRect && len(c.Parts) == 0 {
+			col = color.RGBA{255, 0, 0, 120}
+		}
+		for _, s := range colliderShapes(p, c) {
+			drawShape(screen, s, col, view)
 		}
 	}
 }
 
-func (cm *CollisionManager) drawRect(screen *ebiten.Image, x, y, w, h float64, col color.RGBA) {
-	vector.StrokeRect(screen, float32(x), float32(y), float32(w), float32(h), 1, col, false)
+func (cm *CollisionManager) appendResolvers(resolvers []collisionResolver, a, b *colliderEntry) []collisionResolver {
+	if a.collider.Sensor && b.collider.Sensor {
+		return resolvers
+	}
+	if !a.collider.Sensor && !b.collider.Sensor {
+		resolvers = append(resolvers, cm.getGroupResolvers(a.collider.Group, b.collider.Group)...)
+	}
+	if a.hasHandler {
+		resolvers = append(resolvers, collisionResolver{handler: a.handler})
+	}
+	if b.hasHandler {
+		resolvers = append(resolvers, collisionResolver{handler: b.handler, swap: true})
+	}
+	return resolvers
 }
 
-func (cm *CollisionManager) getHandler(groupA, groupB string) (CollisionHandler, bool) {
+func (cm *CollisionManager) getGroupResolvers(groupA, groupB string) []collisionResolver {
+	key := groupPair{groupA, groupB}
 	cm.mu.RLock()
-	defer cm.mu.RUnlock()
+	resolvers, ok := cm.groupResolvers[key]
+	cm.mu.RUnlock()
+	if ok {
+		return resolvers
+	}
 
-	if handlers, ok := cm.handlers[groupA]; ok {
-		if handler, exists := handlers[groupB]; exists {
-			return handler, true
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	for patternA, handlers := range cm.handlers {
+		for patternB, handler := range handlers {
+			if matchGroup(patternA, groupA) && matchGroup(patternB, groupB) {
+				resolvers = append(resolvers, collisionResolver{handler: handler})
+			} else if matchGroup(patternA, groupB) && matchGroup(patternB, groupA) {
+				resolvers = append(resolvers, collisionResolver{handler: handler, swap: true})
+			}
 		}
 	}
-	return CollisionHandler{}, false
+	cm.groupResolvers[key] = resolvers
+	return resolvers
 }
 
 func (cm *CollisionManager) getComponents(e Entity) (*ColliderComponent, *PositionComponent, *SizeComponent) {
@@ -210,13 +470,3 @@ func (cm *CollisionManager) getComponents(e Entity) (*ColliderComponent, *Positi
 	}
 	return c, p, s
 }
-
-func (cm *CollisionManager) collisionKey(e1, e2 Entity) string {
-	return fmt.Sprintf("%d-%d", e1, e2)
-}
-
-func (cm *CollisionManager) parseCollisionKey(key string) (Entity, Entity) {
-	var e1, e2 Entity
-	fmt.Sscanf(key, "%d-%d", &e1, &e2)
-	return e1, e2
-}
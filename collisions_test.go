@@ -0,0 +1,41 @@
+package gobonsai
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func benchmarkCollisionUpdate(b *testing.B, count int) {
+	Mecs = NewECSManager()
+	cm := NewCollisionManager(Mecs)
+	cm.AddResolve("body", "wall", CollisionHandler{Stay: func(m Manifold) {}})
+
+	rng := rand.New(rand.NewSource(1))
+	side := math.Sqrt(float64(count)) * 32
+	for i := 0; i < count; i++ {
+		e := Mecs.AddEntity()
+		e.AddComponent("position", &PositionComponent{X: rng.Float64() * side, Y: rng.Float64() * side})
+		e.AddComponent("size", &SizeComponent{Width: 16, Height: 16})
+		if i%4 == 0 {
+			e.AddComponent("collider", &ColliderComponent{Group: "wall", Width: 16, Height: 16})
+		} else {
+			e.AddComponent("collider", &ColliderComponent{Group: "body", Shape: ShapeCircle, Radius: 8})
+		}
+	}
+	cm.Update()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		cm.Update()
+	}
+}
+
+func BenchmarkCollisionUpdate1k(b *testing.B) {
+	benchmarkCollisionUpdate(b, 1000)
+}
+
+func BenchmarkCollisionUpdate10k(b *testing.B) {
+	benchmarkCollisionUpdate(b, 10000)
+}
@@ -19,6 +19,7 @@ var (
 	Mcolliders  *CollisionManager
 	Mtilemaps   *TilemapsManager
 	Mphysics    *PhysicsSystem
+	Mcameras    *CamerasManager
 	gtitle      string
 	gwidth      int
 	gheight     int
@@ -39,9 +40,10 @@ func Init(title string, width, height, scale int, embeds embed.FS, samplerate in
 		Mcolliders = NewCollisionManager(Mecs)
 		Mtilemaps = NewTilemapsManager()
 		Maudios = NewAudiosManager(samplerate)
-		Mphysics := NewPhysicsSystem(Mecs)
+		Mphysics = NewPhysicsSystem(Mecs)
 		Mecs.AddSystem("physics", Mphysics)
 		Manimations = NewAnimationsManager()
+		Mcameras = NewCamerasManager()
 		Gravity = gravity
 	})
 }
@@ -57,14 +59,25 @@ type Loop struct{}
 
 func (l *Loop) Update() error {
 	Mscenes.UpdateScenes(1.0 / 60.0)
+	Mcameras.UpdateCameras(1.0 / 60.0)
 	Mcolliders.Update()
+	if Debug {
+		Mtilemaps.CheckReload()
+	}
 	return nil
 }
 
 func (l *Loop) Draw(screen *ebiten.Image) {
 	Mscenes.DrawScenes(screen)
 	if Debug {
-		Mcolliders.Draw(screen)
+		if cameras := Mcameras.GetCameras(); len(cameras) > 0 {
+			for _, camera := range cameras {
+				Mcolliders.DrawCamera(screen, camera)
+			}
+		} else {
+			Mcolliders.Draw(screen)
+		}
+		Mtilemaps.DrawReloadError(screen)
 	}
 }
 
@@ -17,7 +17,9 @@ type Component interface{}
 
 type ColliderComponent struct {
 	Group       string
+	Sensor      bool
 	CrossShape  bool
+	Shape       ShapeType
 	OffsetX     float64
 	OffsetY     float64
 	Width       float64
@@ -26,6 +28,10 @@ type ColliderComponent struct {
 	HorizHeight float64
 	VertWidth   float64
 	VertHeight  float64
+	Radius      float64
+	Rotation    float64
+	Points      []Vector
+	Parts       []ColliderPart
 }
 
 type PositionComponent struct {
@@ -137,6 +143,10 @@ func (em *ECSManager) GetEntityIdent(e Entity) (string, bool) {
 	return ident, true
 }
 
+func (em *ECSManager) LastEntity() Entity {
+	return Entity(atomic.LoadUint32(&em.lastEntityID))
+}
+
 func (em *ECSManager) GetEntityByID(id Entity) (Entity, bool) {
 	_, ok := em.entities.Load(id)
 	return id, ok
@@ -281,7 +291,10 @@ func (em *ECSManager) DrawEntities(screen *ebiten.Image) {
 }
 
 func (em *ECSManager) GetEntitiesWithComponents(names ...string) []Entity {
-	var result []Entity
+	return em.AppendEntitiesWithComponents(nil, names...)
+}
+
+func (em *ECSManager) AppendEntitiesWithComponents(result []Entity, names ...string) []Entity {
 	em.entities.Range(func(k, v interface{}) bool {
 		e := k.(Entity)
 		compVal, _ := em.components.Load(e)
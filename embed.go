@@ -52,3 +52,27 @@ func (em *EmbedManager) GetFileAsImage(filePath string) *ebiten.Image {
 	}
 	return ebiten.NewImageFromImage(img)
 }
+
+func (em *EmbedManager) ReadDir(dirPath string) []string {
+	var entries []os.DirEntry
+	var err error
+
+	if Debug {
+		entries, err = os.ReadDir(dirPath)
+	} else {
+		dirPath = strings.ReplaceAll(dirPath, "\\", "/")
+		entries, err = em.embeddedFiles.ReadDir(dirPath)
+	}
+
+	if err != nil {
+		em.logger.Error("Failed to read embedded dir:", dirPath)
+		return nil
+	}
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			names = append(names, entry.Name())
+		}
+	}
+	return names
+}
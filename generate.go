@@ -0,0 +1,231 @@
+package gobonsai
+
+import (
+	"image"
+	"math/rand"
+)
+
+type GenGrid struct {
+	Width  int
+	Height int
+	Floor  []bool
+	Rooms  []image.Rectangle
+}
+
+type BSPOptions struct {
+	Seed        int64
+	MinLeafSize int
+	MinRoomSize int
+	Padding     int
+}
+
+type CaveOptions struct {
+	Seed        int64
+	FillChance  float64
+	Steps       int
+	WallLimit   int
+	KeepLargest bool
+}
+
+func NewGenGrid(width, height int) *GenGrid {
+	return &GenGrid{Width: width, Height: height, Floor: make([]bool, width*height)}
+}
+
+func (g *GenGrid) IsFloor(x, y int) bool {
+	return x >= 0 && y >= 0 && x < g.Width && y < g.Height && g.Floor[y*g.Width+x]
+}
+
+func (g *GenGrid) SetFloor(x, y int, floor bool) {
+	if x >= 0 && y >= 0 && x < g.Width && y < g.Height {
+		g.Floor[y*g.Width+x] = floor
+	}
+}
+
+func (g *GenGrid) carve(r image.Rectangle) {
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			g.SetFloor(x, y, true)
+		}
+	}
+}
+
+func (g *GenGrid) corridor(rng *rand.Rand, a, b image.Point) {
+	corner := image.Pt(b.X, a.Y)
+	if rng.Intn(2) == 0 {
+		corner = image.Pt(a.X, b.Y)
+	}
+	for _, seg := range [2][2]image.Point{{a, corner}, {corner, b}} {
+		from, to := seg[0], seg[1]
+		r := image.Rect(from.X, from.Y, to.X, to.Y).Canon()
+		g.carve(image.Rect(r.Min.X, r.Min.Y, r.Max.X+1, r.Max.Y+1))
+	}
+}
+
+func center(r image.Rectangle) image.Point {
+	return image.Pt((r.Min.X+r.Max.X)/2, (r.Min.Y+r.Max.Y)/2)
+}
+
+func GenerateBSP(width, height int, opts BSPOptions) *GenGrid {
+	if opts.MinLeafSize <= 0 {
+		opts.MinLeafSize = 8
+	}
+	if opts.MinRoomSize <= 0 {
+		opts.MinRoomSize = 4
+	}
+	if opts.Padding <= 0 {
+		opts.Padding = 1
+	}
+	g := NewGenGrid(width, height)
+	rng := rand.New(rand.NewSource(opts.Seed))
+	leaf := opts.MinLeafSize
+
+	var split func(r image.Rectangle) image.Rectangle
+	split = func(r image.Rectangle) image.Rectangle {
+		canX, canY := r.Dx() >= 2*leaf, r.Dy() >= 2*leaf
+		if !canX && !canY {
+			maxW, maxH := r.Dx()-2*opts.Padding, r.Dy()-2*opts.Padding
+			if maxW < opts.MinRoomSize || maxH < opts.MinRoomSize {
+				return image.Rectangle{}
+			}
+			w := opts.MinRoomSize + rng.Intn(maxW-opts.MinRoomSize+1)
+			h := opts.MinRoomSize + rng.Intn(maxH-opts.MinRoomSize+1)
+			x := r.Min.X + opts.Padding + rng.Intn(maxW-w+1)
+			y := r.Min.Y + opts.Padding + rng.Intn(maxH-h+1)
+			room := image.Rect(x, y, x+w, y+h)
+			g.carve(room)
+			g.Rooms = append(g.Rooms, room)
+			return room
+		}
+		vertical := canX
+		switch {
+		case canX && canY && r.Dx()*4 > r.Dy()*5:
+			vertical = true
+		case canX && canY && r.Dy()*4 > r.Dx()*5:
+			vertical = false
+		case canX && canY:
+			vertical = rng.Intn(2) == 0
+		}
+		var a, b image.Rectangle
+		if vertical {
+			at := r.Min.X + leaf + rng.Intn(r.Dx()-2*leaf+1)
+			a, b = split(image.Rect(r.Min.X, r.Min.Y, at, r.Max.Y)), split(image.Rect(at, r.Min.Y, r.Max.X, r.Max.Y))
+		} else {
+			at := r.Min.Y + leaf + rng.Intn(r.Dy()-2*leaf+1)
+			a, b = split(image.Rect(r.Min.X, r.Min.Y, r.Max.X, at)), split(image.Rect(r.Min.X, at, r.Max.X, r.Max.Y))
+		}
+		switch {
+		case a.Empty():
+			return b
+		case b.Empty():
+			return a
+		}
+		g.corridor(rng, center(a), center(b))
+		if rng.Intn(2) == 0 {
+			return a
+		}
+		return b
+	}
+	split(image.Rect(0, 0, width, height))
+	return g
+}
+
+func GenerateCave(width, height int, opts CaveOptions) *GenGrid {
+	if opts.FillChance <= 0 {
+		opts.FillChance = 0.45
+	}
+	if opts.Steps <= 0 {
+		opts.Steps = 5
+	}
+	if opts.WallLimit <= 0 {
+		opts.WallLimit = 5
+	}
+	g := NewGenGrid(width, height)
+	rng := rand.New(rand.NewSource(opts.Seed))
+	for y := 1; y < height-1; y++ {
+		for x := 1; x < width-1; x++ {
+			g.SetFloor(x, y, rng.Float64() >= opts.FillChance)
+		}
+	}
+
+	next := make([]bool, len(g.Floor))
+	for step := 0; step < opts.Steps; step++ {
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				walls := 0
+				for dy := -1; dy <= 1; dy++ {
+					for dx := -1; dx <= 1; dx++ {
+						if (dx != 0 || dy != 0) && !g.IsFloor(x+dx, y+dy) {
+							walls++
+						}
+					}
+				}
+				idx := y*width + x
+				switch {
+				case x == 0 || y == 0 || x == width-1 || y == height-1:
+					next[idx] = false
+				case walls >= opts.WallLimit:
+					next[idx] = false
+				case walls < opts.WallLimit-1:
+					next[idx] = true
+				default:
+					next[idx] = g.Floor[idx]
+				}
+			}
+		}
+		g.Floor, next = next, g.Floor
+	}
+
+	regions := g.regions()
+	if opts.KeepLargest && len(regions) > 1 {
+		largest := 0
+		for i, region := range regions {
+			if len(region) > len(regions[largest]) {
+				largest = i
+			}
+		}
+		for i, region := range regions {
+			if i == largest {
+				continue
+			}
+			for _, pt := range region {
+				g.SetFloor(pt.X, pt.Y, false)
+			}
+		}
+		regions = regions[largest : largest+1]
+	}
+	for _, region := range regions {
+		var bounds image.Rectangle
+		for _, pt := range region {
+			bounds = bounds.Union(image.Rect(pt.X, pt.Y, pt.X+1, pt.Y+1))
+		}
+		g.Rooms = append(g.Rooms, bounds)
+	}
+	return g
+}
+
+func (g *GenGrid) regions() [][]image.Point {
+	seen := make([]bool, len(g.Floor))
+	var regions [][]image.Point
+	for start := range g.Floor {
+		if !g.Floor[start] || seen[start] {
+			continue
+		}
+		seen[start] = true
+		stack := []image.Point{image.Pt(start%g.Width, start/g.Width)}
+		var region []image.Point
+		for len(stack) > 0 {
+			pt := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			region = append(region, pt)
+			for _, d := range [4]image.Point{{1, 0}, {-1, 0}, {0, 1}, {0, -1}} {
+				n := pt.Add(d)
+				if g.IsFloor(n.X, n.Y) && !seen[n.Y*g.Width+n.X] {
+					seen[n.Y*g.Width+n.X] = true
+					stack = append(stack, n)
+				}
+			}
+		}
+		regions = append(regions, region)
+	}
+	return regions
+}
@@ -6,6 +6,7 @@ require (
 	github.com/314isme/gonekko v1.0.0
 	github.com/fatih/color v1.18.0
 	github.com/hajimehoshi/ebiten/v2 v2.8.8
+	github.com/klauspost/compress v1.18.0
 )
 
 require (
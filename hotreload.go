@@ -0,0 +1,103 @@
+package gobonsai
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+)
+
+const reloadInterval = 30
+
+func statSources(paths []string) map[string]time.Time {
+	times := make(map[string]time.Time, len(paths))
+	for _, path := range paths {
+		if info, err := os.Stat(path); err == nil {
+			times[path] = info.ModTime()
+		}
+	}
+	return times
+}
+
+func (tm *TileMap) changed() bool {
+	for path, t := range tm.modTimes {
+		if info, err := os.Stat(path); err == nil && !info.ModTime().Equal(t) {
+			return true
+		}
+	}
+	return false
+}
+
+func (tmm *TilemapsManager) CheckReload() {
+	tmm.mu.Lock()
+	tmm.reloadTicks++
+	if tmm.reloadTicks < reloadInterval {
+		tmm.mu.Unlock()
+		return
+	}
+	tmm.reloadTicks = 0
+	var changed []*TileMap
+	for _, tm := range tmm.tileMaps {
+		if tm.path != "" && tm.changed() {
+			tm.modTimes = statSources(tm.sources)
+			changed = append(changed, tm)
+		}
+	}
+	tmm.mu.Unlock()
+
+	for _, tm := range changed {
+		tmm.reloadTilemap(tm)
+	}
+}
+
+func (tmm *TilemapsManager) reloadTilemap(old *TileMap) {
+	tm, err := tmm.loadTilemap(old.name, old.path)
+	tmm.mu.Lock()
+	if err != nil {
+		tmm.reloadErr = fmt.Errorf("failed to reload tilemap %s: %w", old.name, err)
+		tmm.mu.Unlock()
+		tmm.logger.Error(tmm.reloadErr)
+		return
+	}
+	tmm.reloadErr = nil
+	if tmm.tileMaps[old.name] != old {
+		tmm.mu.Unlock()
+		return
+	}
+	if tmm.streaming && tmm.world != nil {
+		if r, ok := tmm.world.rooms[old.name]; ok {
+			tmm.unloadRoom(r)
+			tmm.storeRoom(r, tm)
+			tmm.mu.Unlock()
+			tmm.logger.Info("Room reloaded:", old.name)
+			return
+		}
+	}
+	tmm.tileMaps[old.name] = tm
+	old.release()
+	current, scale := old.name == tmm.currentTileMap, tmm.scale
+	tmm.mu.Unlock()
+
+	tmm.logger.Info("Tilemap reloaded:", old.name)
+	if current {
+		if err := tmm.SetTilemap(old.name, scale); err != nil {
+			tmm.mu.Lock()
+			tmm.reloadErr = fmt.Errorf("failed to respawn tilemap %s: %w", old.name, err)
+			tmm.mu.Unlock()
+		}
+	}
+}
+
+func (tmm *TilemapsManager) GetReloadError() error {
+	tmm.mu.Lock()
+	defer tmm.mu.Unlock()
+	return tmm.reloadErr
+}
+
+func (tmm *TilemapsManager) DrawReloadError(screen *ebiten.Image) {
+	if err := tmm.GetReloadError(); err != nil {
+		ebitenutil.DebugPrintAt(screen, err.Error(), 4, 4)
+	}
+}
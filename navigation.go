@@ -0,0 +1,631 @@
+package gobonsai
+
+import (
+	"container/heap"
+	"fmt"
+	"image"
+	"math"
+	"slices"
+	"sync"
+)
+
+type NavAction int
+
+const (
+	NavWalk NavAction = iota
+	NavJump
+	NavFall
+)
+
+type navCell uint8
+
+const (
+	navOpen navCell = iota
+	navSolid
+	navPlatform
+)
+
+type NavOptions struct {
+	Layers       []string
+	Diagonal     bool
+	JumpPoint    bool
+	Platformer   bool
+	JumpHeight   int
+	JumpDistance int
+	Smooth       bool
+}
+
+type PathNode struct {
+	X      float64
+	Y      float64
+	TileX  int
+	TileY  int
+	Action NavAction
+}
+
+type NavGrid struct {
+	Bounds  image.Rectangle
+	Options NavOptions
+
+	tm         *TileMap
+	cells      []navCell
+	costs      []float64
+	weighted   int
+	tileWidth  float64
+	tileHeight float64
+	worldX     float64
+	worldY     float64
+	mu         sync.RWMutex
+}
+
+type navEdge struct {
+	to     int
+	cost   float64
+	action NavAction
+}
+
+type navItem struct {
+	node     int
+	priority float64
+	index    int
+}
+
+type navQueue []*navItem
+
+func (q navQueue) Len() int { return len(q) }
+
+func (q navQueue) Less(i, j int) bool { return q[i].priority < q[j].priority }
+
+func (q navQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *navQueue) Push(x interface{}) {
+	item := x.(*navItem)
+	item.index = len(*q)
+	*q = append(*q, item)
+}
+
+func (q *navQueue) Pop() interface{} {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
+
+func (tmm *TilemapsManager) BuildNavGrid(opts NavOptions) (*NavGrid, error) {
+	tmm.mu.Lock()
+	defer tmm.mu.Unlock()
+	tm, ok := tmm.currentMap()
+	if !ok {
+		return nil, fmt.Errorf("no tilemap set")
+	}
+	if !tm.Map.isOrthogonal() {
+		return nil, fmt.Errorf("navigation grid requires an orthogonal map, got %s", tm.Map.Orientation)
+	}
+	if opts.Platformer && opts.JumpHeight == 0 && opts.JumpDistance == 0 {
+		opts.JumpHeight, opts.JumpDistance = 3, 4
+	}
+	var bounds image.Rectangle
+	for _, layer := range tm.navLayers(opts.Layers) {
+		bounds = bounds.Union(layer.tileBounds())
+	}
+	g := &NavGrid{
+		Bounds:     bounds,
+		Options:    opts,
+		tm:         tm,
+		cells:      make([]navCell, bounds.Dx()*bounds.Dy()),
+		costs:      make([]float64, bounds.Dx()*bounds.Dy()),
+		weighted:   bounds.Dx() * bounds.Dy(),
+		tileWidth:  float64(tm.Map.TileWidth) * tmm.scale,
+		tileHeight: float64(tm.Map.TileHeight) * tmm.scale,
+		worldX:     tm.WorldX * tmm.scale,
+		worldY:     tm.WorldY * tmm.scale,
+	}
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			g.refresh(x, y)
+		}
+	}
+	tm.navGrids = append(tm.navGrids, g)
+	return g, nil
+}
+
+func (tmm *TilemapsManager) ReleaseNavGrid(g *NavGrid) {
+	tmm.mu.Lock()
+	defer tmm.mu.Unlock()
+	if g.tm != nil {
+		g.tm.navGrids = slices.DeleteFunc(g.tm.navGrids, func(other *NavGrid) bool {
+			return other == g
+		})
+	}
+}
+
+func (tm *TileMap) navLayers(names []string) []*Layer {
+	var layers []*Layer
+	if len(names) > 0 {
+		for _, name := range names {
+			if l := tm.findLayer(name); l != nil && l.Type == "tilelayer" {
+				layers = append(layers, l)
+			}
+		}
+		return layers
+	}
+	var collect func(ls []Layer)
+	collect = func(ls []Layer) {
+		for i := range ls {
+			if ls[i].Type == "tilelayer" {
+				layers = append(layers, &ls[i])
+			}
+			collect(ls[i].Layers)
+		}
+	}
+	collect(tm.Map.Layers)
+	return layers
+}
+
+func (g *NavGrid) refresh(x, y int) {
+	idx, ok := g.index(x, y)
+	if !ok {
+		return
+	}
+	cell, cost := navOpen, 1.0
+	for _, layer := range g.tm.navLayers(g.Options.Layers) {
+		gid, _ := DecodeGID(layer.gidAt(x, y))
+		tile, ok := g.tm.Tiles[gid]
+		if gid == 0 || !ok {
+			continue
+		}
+		if c, ok := tile.Properties["cost"].(float64); ok && c > cost {
+			cost = c
+		}
+		switch walkable, set := tile.Properties["walkable"].(bool); {
+		case set && walkable:
+		case set && !walkable, tileFlag(tile.Properties, "solid"), len(tile.Collision) > 0 && !tileFlag(tile.Properties, "oneway"):
+			cell = navSolid
+		case tileFlag(tile.Properties, "oneway") && cell == navOpen:
+			cell = navPlatform
+		}
+	}
+	g.cells[idx] = cell
+	g.setCost(idx, cost)
+}
+
+func (g *NavGrid) setCost(idx int, cost float64) {
+	switch {
+	case g.costs[idx] == 1 && cost != 1:
+		g.weighted++
+	case g.costs[idx] != 1 && cost == 1:
+		g.weighted--
+	}
+	g.costs[idx] = cost
+}
+
+func (g *NavGrid) Refresh(x, y int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.refresh(x, y)
+}
+
+func (g *NavGrid) SetBlocked(x, y int, blocked bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if idx, ok := g.index(x, y); ok {
+		g.cells[idx] = navOpen
+		if blocked {
+			g.cells[idx] = navSolid
+		}
+	}
+}
+
+func (g *NavGrid) SetCost(x, y int, cost float64) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if idx, ok := g.index(x, y); ok {
+		g.setCost(idx, max(cost, 1))
+	}
+}
+
+func (g *NavGrid) IsWalkable(x, y int) bool {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.passable(x, y)
+}
+
+func (g *NavGrid) index(x, y int) (int, bool) {
+	if !image.Pt(x, y).In(g.Bounds) {
+		return 0, false
+	}
+	return (y-g.Bounds.Min.Y)*g.Bounds.Dx() + (x - g.Bounds.Min.X), true
+}
+
+func (g *NavGrid) point(idx int) (int, int) {
+	return g.Bounds.Min.X + idx%g.Bounds.Dx(), g.Bounds.Min.Y + idx/g.Bounds.Dx()
+}
+
+func (g *NavGrid) passable(x, y int) bool {
+	idx, ok := g.index(x, y)
+	return ok && g.cells[idx] != navSolid
+}
+
+func (g *NavGrid) standing(x, y int) bool {
+	if !g.passable(x, y) {
+		return false
+	}
+	idx, ok := g.index(x, y+1)
+	return !ok || g.cells[idx] != navOpen
+}
+
+func (g *NavGrid) WorldToTile(x, y float64) (int, int) {
+	return int(math.Floor((x - g.worldX) / g.tileWidth)), int(math.Floor((y - g.worldY) / g.tileHeight))
+}
+
+func (g *NavGrid) TileCenter(x, y int) (float64, float64) {
+	return g.worldX + (float64(x)+0.5)*g.tileWidth, g.worldY + (float64(y)+0.5)*g.tileHeight
+}
+
+func (g *NavGrid) FindPath(fromX, fromY, toX, toY float64) []PathNode {
+	sx, sy := g.WorldToTile(fromX, fromY)
+	tx, ty := g.WorldToTile(toX, toY)
+	return g.FindTilePath(sx, sy, tx, ty)
+}
+
+func (g *NavGrid) FindTilePath(sx, sy, tx, ty int) []PathNode {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	if g.Options.Platformer {
+		sy, ty = g.land(sx, sy), g.land(tx, ty)
+	}
+	start, ok1 := g.index(sx, sy)
+	goal, ok2 := g.index(tx, ty)
+	if !ok1 || !ok2 || !g.passable(sx, sy) || !g.passable(tx, ty) {
+		return nil
+	}
+
+	var nodes []int
+	var actions []NavAction
+	if g.Options.JumpPoint && g.Options.Diagonal && !g.Options.Platformer && g.weighted == 0 {
+		nodes, actions = g.search(start, goal, g.jumpSuccessors(goal))
+	} else {
+		nodes, actions = g.search(start, goal, g.successors)
+	}
+	if nodes == nil {
+		return nil
+	}
+	if g.Options.Smooth {
+		nodes, actions = g.smooth(nodes, actions)
+	}
+	path := make([]PathNode, len(nodes))
+	for i, n := range nodes {
+		x, y := g.point(n)
+		wx, wy := g.TileCenter(x, y)
+		path[i] = PathNode{X: wx, Y: wy, TileX: x, TileY: y, Action: actions[i]}
+	}
+	return path
+}
+
+func (g *NavGrid) land(x, y int) int {
+	for ; y < g.Bounds.Max.Y; y++ {
+		if g.standing(x, y) {
+			return y
+		}
+	}
+	return y
+}
+
+func (g *NavGrid) heuristic(a, b int) float64 {
+	ax, ay := g.point(a)
+	bx, by := g.point(b)
+	dx, dy := math.Abs(float64(ax-bx)), math.Abs(float64(ay-by))
+	if g.Options.Diagonal && !g.Options.Platformer {
+		return math.Max(dx, dy) + (math.Sqrt2-1)*math.Min(dx, dy)
+	}
+	return dx + dy
+}
+
+func (g *NavGrid) search(start, goal int, successors func(node, parent int, edges []navEdge) []navEdge) ([]int, []NavAction) {
+	size := len(g.cells)
+	gScore := make([]float64, size)
+	parents := make([]int, size)
+	actions := make([]NavAction, size)
+	closed := make([]bool, size)
+	for i := range gScore {
+		gScore[i] = math.Inf(1)
+		parents[i] = -1
+	}
+	gScore[start] = 0
+	open := &navQueue{{node: start, priority: g.heuristic(start, goal)}}
+	var edges []navEdge
+	for open.Len() > 0 {
+		node := heap.Pop(open).(*navItem).node
+		if node == goal {
+			var nodes []int
+			var acts []NavAction
+			for n := goal; n != -1; n = parents[n] {
+				nodes = append(nodes, n)
+				acts = append(acts, actions[n])
+			}
+			for i, j := 0, len(nodes)-1; i < j; i, j = i+1, j-1 {
+				nodes[i], nodes[j] = nodes[j], nodes[i]
+				acts[i], acts[j] = acts[j], acts[i]
+			}
+			return nodes, acts
+		}
+		if closed[node] {
+			continue
+		}
+		closed[node] = true
+		edges = successors(node, parents[node], edges[:0])
+		for _, e := range edges {
+			if closed[e.to] {
+				continue
+			}
+			score := gScore[node] + e.cost
+			if score < gScore[e.to] {
+				gScore[e.to] = score
+				parents[e.to] = node
+				actions[e.to] = e.action
+				heap.Push(open, &navItem{node: e.to, priority: score + g.heuristic(e.to, goal)})
+			}
+		}
+	}
+	return nil, nil
+}
+
+func (g *NavGrid) successors(node, parent int, edges []navEdge) []navEdge {
+	x, y := g.point(node)
+	if g.Options.Platformer {
+		return g.platformSuccessors(x, y, edges)
+	}
+	for _, d := range [8]image.Point{{1, 0}, {-1, 0}, {0, 1}, {0, -1}, {1, 1}, {1, -1}, {-1, 1}, {-1, -1}} {
+		nx, ny := x+d.X, y+d.Y
+		if !g.passable(nx, ny) {
+			continue
+		}
+		step := 1.0
+		if d.X != 0 && d.Y != 0 {
+			if !g.Options.Diagonal || !g.passable(nx, y) || !g.passable(x, ny) {
+				continue
+			}
+			step = math.Sqrt2
+		}
+		idx, _ := g.index(nx, ny)
+		edges = append(edges, navEdge{to: idx, cost: step * g.costs[idx]})
+	}
+	return edges
+}
+
+func (g *NavGrid) platformSuccessors(x, y int, edges []navEdge) []navEdge {
+	add := func(nx, ny int, cost float64, action NavAction) {
+		if idx, ok := g.index(nx, ny); ok {
+			edges = append(edges, navEdge{to: idx, cost: cost * g.costs[idx], action: action})
+		}
+	}
+	for _, dx := range [2]int{-1, 1} {
+		nx := x + dx
+		switch {
+		case g.standing(nx, y):
+			add(nx, y, 1, NavWalk)
+		case g.passable(nx, y):
+			ny := y + 1
+			for g.passable(nx, ny) && !g.standing(nx, ny) {
+				ny++
+			}
+			if g.standing(nx, ny) {
+				add(nx, ny, float64(1+ny-y), NavFall)
+			}
+		}
+	}
+	jh, jd := g.Options.JumpHeight, g.Options.JumpDistance
+	for ty := y - jh; ty <= y+jh; ty++ {
+		for tx := x - jd; tx <= x+jd; tx++ {
+			if (tx == x && ty == y) || (ty == y && abs(tx-x) <= 1) || !g.standing(tx, ty) {
+				continue
+			}
+			if g.canJump(x, y, tx, ty) {
+				add(tx, ty, float64(abs(tx-x)+abs(ty-y))+1, NavJump)
+			}
+		}
+	}
+	return edges
+}
+
+func (g *NavGrid) canJump(x, y, tx, ty int) bool {
+	apex := min(y, ty) - 1
+	if y-apex > g.Options.JumpHeight+1 {
+		return false
+	}
+	for cy := apex; cy <= y; cy++ {
+		if !g.passable(x, cy) {
+			return false
+		}
+	}
+	step := 1
+	if tx < x {
+		step = -1
+	}
+	for cx := x; cx != tx+step; cx += step {
+		if !g.passable(cx, apex) {
+			return false
+		}
+	}
+	for cy := apex; cy <= ty; cy++ {
+		if !g.passable(tx, cy) {
+			return false
+		}
+	}
+	return true
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+func sign(v int) int {
+	switch {
+	case v > 0:
+		return 1
+	case v < 0:
+		return -1
+	default:
+		return 0
+	}
+}
+
+func (g *NavGrid) jumpSuccessors(goal int) func(node, parent int, edges []navEdge) []navEdge {
+	return func(node, parent int, edges []navEdge) []navEdge {
+		x, y := g.point(node)
+		for _, n := range g.prunedNeighbors(x, y, parent) {
+			jx, jy, ok := g.jump(n.X, n.Y, x, y, goal)
+			if !ok {
+				continue
+			}
+			idx, _ := g.index(jx, jy)
+			dx, dy := float64(abs(jx-x)), float64(abs(jy-y))
+			edges = append(edges, navEdge{to: idx, cost: math.Max(dx, dy) + (math.Sqrt2-1)*math.Min(dx, dy)})
+		}
+		return edges
+	}
+}
+
+func (g *NavGrid) prunedNeighbors(x, y, parent int) []image.Point {
+	var out []image.Point
+	if parent < 0 {
+		for _, d := range [8]image.Point{{1, 0}, {-1, 0}, {0, 1}, {0, -1}, {1, 1}, {1, -1}, {-1, 1}, {-1, -1}} {
+			nx, ny := x+d.X, y+d.Y
+			if g.passable(nx, ny) && (d.X == 0 || d.Y == 0 || (g.passable(nx, y) && g.passable(x, ny))) {
+				out = append(out, image.Pt(nx, ny))
+			}
+		}
+		return out
+	}
+	px, py := g.point(parent)
+	dx, dy := sign(x-px), sign(y-py)
+	switch {
+	case dx != 0 && dy != 0:
+		if g.passable(x, y+dy) {
+			out = append(out, image.Pt(x, y+dy))
+		}
+		if g.passable(x+dx, y) {
+			out = append(out, image.Pt(x+dx, y))
+		}
+		if g.passable(x, y+dy) && g.passable(x+dx, y) {
+			out = append(out, image.Pt(x+dx, y+dy))
+		}
+	case dx != 0:
+		next, down, up := g.passable(x+dx, y), g.passable(x, y+1), g.passable(x, y-1)
+		if next {
+			out = append(out, image.Pt(x+dx, y))
+			if down {
+				out = append(out, image.Pt(x+dx, y+1))
+			}
+			if up {
+				out = append(out, image.Pt(x+dx, y-1))
+			}
+		}
+		if down {
+			out = append(out, image.Pt(x, y+1))
+		}
+		if up {
+			out = append(out, image.Pt(x, y-1))
+		}
+	default:
+		next, right, left := g.passable(x, y+dy), g.passable(x+1, y), g.passable(x-1, y)
+		if next {
+			out = append(out, image.Pt(x, y+dy))
+			if right {
+				out = append(out, image.Pt(x+1, y+dy))
+			}
+			if left {
+				out = append(out, image.Pt(x-1, y+dy))
+			}
+		}
+		if right {
+			out = append(out, image.Pt(x+1, y))
+		}
+		if left {
+			out = append(out, image.Pt(x-1, y))
+		}
+	}
+	return out
+}
+
+func (g *NavGrid) jump(x, y, px, py, goal int) (int, int, bool) {
+	for {
+		dx, dy := x-px, y-py
+		if !g.passable(x, y) {
+			return 0, 0, false
+		}
+		if idx, _ := g.index(x, y); idx == goal {
+			return x, y, true
+		}
+		if dx != 0 && dy != 0 {
+			if _, _, ok := g.jump(x+dx, y, x, y, goal); ok {
+				return x, y, true
+			}
+			if _, _, ok := g.jump(x, y+dy, x, y, goal); ok {
+				return x, y, true
+			}
+		} else if dx != 0 {
+			if (g.passable(x, y-1) && !g.passable(x-dx, y-1)) || (g.passable(x, y+1) && !g.passable(x-dx, y+1)) {
+				return x, y, true
+			}
+		} else if (g.passable(x-1, y) && !g.passable(x-1, y-dy)) || (g.passable(x+1, y) && !g.passable(x+1, y-dy)) {
+			return x, y, true
+		}
+		if !g.passable(x+dx, y) || !g.passable(x, y+dy) {
+			return 0, 0, false
+		}
+		px, py = x, y
+		x, y = x+dx, y+dy
+	}
+}
+
+func (g *NavGrid) smooth(nodes []int, actions []NavAction) ([]int, []NavAction) {
+	if len(nodes) < 3 {
+		return nodes, actions
+	}
+	outNodes, outActions := []int{nodes[0]}, []NavAction{actions[0]}
+	anchor := 0
+	for i := 1; i < len(nodes)-1; i++ {
+		if g.Options.Platformer {
+			ax, ay := g.point(nodes[anchor])
+			nx, ny := g.point(nodes[i+1])
+			if actions[i] == NavWalk && actions[i+1] == NavWalk && ay == ny && abs(nx-ax) == i+1-anchor {
+				continue
+			}
+		} else if g.lineOfSight(nodes[anchor], nodes[i+1]) {
+			continue
+		}
+		outNodes, outActions = append(outNodes, nodes[i]), append(outActions, actions[i])
+		anchor = i
+	}
+	last := len(nodes) - 1
+	return append(outNodes, nodes[last]), append(outActions, actions[last])
+}
+
+func (g *NavGrid) lineOfSight(a, b int) bool {
+	ax, ay := g.point(a)
+	bx, by := g.point(b)
+	if g.costs[a] != g.costs[b] {
+		return false
+	}
+	steps := 4 * max(abs(bx-ax), abs(by-ay))
+	for i := 1; i < steps; i++ {
+		t := float64(i) / float64(steps)
+		fx, fy := float64(ax)+0.5+float64(bx-ax)*t, float64(ay)+0.5+float64(by-ay)*t
+		for _, c := range [4]Vector{{-0.2, -0.2}, {0.2, -0.2}, {-0.2, 0.2}, {0.2, 0.2}} {
+			x, y := int(math.Floor(fx+c.X)), int(math.Floor(fy+c.Y))
+			idx, ok := g.index(x, y)
+			if !ok || g.cells[idx] == navSolid || g.costs[idx] != g.costs[a] {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,290 @@
+package gobonsai
+
+import (
+	"image"
+	"math"
+)
+
+type hexParams struct {
+	tileWidth   float64
+	tileHeight  float64
+	sideLengthX float64
+	sideLengthY float64
+	sideOffsetX float64
+	sideOffsetY float64
+	columnWidth float64
+	rowHeight   float64
+	staggerX    bool
+	staggerEven bool
+	hexagonal   bool
+}
+
+func (m *Map) isOrthogonal() bool {
+	return m.Orientation == "" || m.Orientation == "orthogonal"
+}
+
+func (m *Map) isStaggered() bool {
+	return m.Orientation == "staggered" || m.Orientation == "hexagonal"
+}
+
+func (m *Map) hexParams() hexParams {
+	p := hexParams{
+		tileWidth:   float64(m.TileWidth &^ 1),
+		tileHeight:  float64(m.TileHeight &^ 1),
+		staggerX:    m.StaggerAxis == "x",
+		staggerEven: m.StaggerIndex == "even",
+		hexagonal:   m.Orientation == "hexagonal",
+	}
+	if p.hexagonal {
+		if p.staggerX {
+			p.sideLengthX = float64(m.HexSideLength)
+		} else {
+			p.sideLengthY = float64(m.HexSideLength)
+		}
+	}
+	p.sideOffsetX = (p.tileWidth - p.sideLengthX) / 2
+	p.sideOffsetY = (p.tileHeight - p.sideLengthY) / 2
+	p.columnWidth = p.sideOffsetX + p.sideLengthX
+	p.rowHeight = p.sideOffsetY + p.sideLengthY
+	return p
+}
+
+func (p hexParams) doStaggerX(x int) bool {
+	return p.staggerX && (x&1 != 0) != p.staggerEven
+}
+
+func (p hexParams) doStaggerY(y int) bool {
+	return !p.staggerX && (y&1 != 0) != p.staggerEven
+}
+
+func (m *Map) isoOriginX() float64 {
+	return float64(m.Height*m.TileWidth) / 2
+}
+
+func (m *Map) tileToPixel(x, y int) (float64, float64) {
+	tw, th := float64(m.TileWidth), float64(m.TileHeight)
+	switch {
+	case m.Orientation == "isometric":
+		return float64(x-y)*tw/2 + m.isoOriginX() - tw/2, float64(x+y) * th / 2
+	case m.isStaggered():
+		p := m.hexParams()
+		if p.staggerX {
+			py := float64(y) * (p.tileHeight + p.sideLengthY)
+			if p.doStaggerX(x) {
+				py += p.rowHeight
+			}
+			return float64(x) * p.columnWidth, py
+		}
+		px := float64(x) * (p.tileWidth + p.sideLengthX)
+		if p.doStaggerY(y) {
+			px += p.columnWidth
+		}
+		return px, float64(y) * p.rowHeight
+	default:
+		return float64(x) * tw, float64(y) * th
+	}
+}
+
+func (m *Map) pixelToTile(px, py float64) (int, int) {
+	tw, th := float64(m.TileWidth), float64(m.TileHeight)
+	if tw <= 0 || th <= 0 {
+		return 0, 0
+	}
+	switch {
+	case m.Orientation == "isometric":
+		tileY := py / th
+		tileX := (px - m.isoOriginX()) / tw
+		return int(math.Floor(tileY + tileX)), int(math.Floor(tileY - tileX))
+	case m.isStaggered():
+		return m.pixelToStaggeredTile(px, py)
+	default:
+		return int(math.Floor(px / tw)), int(math.Floor(py / th))
+	}
+}
+
+func (m *Map) pixelToStaggeredTile(px, py float64) (int, int) {
+	p := m.hexParams()
+	if p.staggerX {
+		if p.staggerEven {
+			px -= p.tileWidth
+		} else {
+			px -= p.sideOffsetX
+		}
+	} else if p.staggerEven {
+		py -= p.tileHeight
+	} else {
+		py -= p.sideOffsetY
+	}
+	refX := int(math.Floor(px / (p.columnWidth * 2)))
+	refY := int(math.Floor(py / (p.rowHeight * 2)))
+	relX := px - float64(refX)*p.columnWidth*2
+	relY := py - float64(refY)*p.rowHeight*2
+	if p.staggerX {
+		refX *= 2
+		if p.staggerEven {
+			refX++
+		}
+	} else {
+		refY *= 2
+		if p.staggerEven {
+			refY++
+		}
+	}
+
+	var centers [4]Vector
+	var offsets [4]image.Point
+	if p.staggerX {
+		left := p.sideLengthX / 2
+		cx, cy := left+p.columnWidth, p.tileHeight/2
+		centers = [4]Vector{{left, cy}, {cx, cy - p.rowHeight}, {cx, cy + p.rowHeight}, {cx + p.columnWidth, cy}}
+		offsets = [4]image.Point{{0, 0}, {1, -1}, {1, 0}, {2, 0}}
+	} else {
+		top := p.sideLengthY / 2
+		cx, cy := p.tileWidth/2, top+p.rowHeight
+		centers = [4]Vector{{cx, top}, {cx - p.columnWidth, cy}, {cx + p.columnWidth, cy}, {cx, cy + p.rowHeight}}
+		offsets = [4]image.Point{{0, 0}, {-1, 1}, {0, 1}, {0, 2}}
+	}
+
+	nearest, best := 0, math.Inf(1)
+	for i, c := range centers {
+		dx, dy := math.Abs(c.X-relX), math.Abs(c.Y-relY)
+		d := dx*dx + dy*dy
+		if !p.hexagonal {
+			d = dx/p.tileWidth + dy/p.tileHeight
+		}
+		if d < best {
+			nearest, best = i, d
+		}
+	}
+	return refX + offsets[nearest].X, refY + offsets[nearest].Y
+}
+
+func (m *Map) pixelToScreen(x, y float64) (float64, float64) {
+	if m.Orientation != "isometric" || m.TileHeight == 0 {
+		return x, y
+	}
+	tw, th := float64(m.TileWidth), float64(m.TileHeight)
+	tileX, tileY := x/th, y/th
+	return (tileX-tileY)*tw/2 + m.isoOriginX(), (tileX + tileY) * th / 2
+}
+
+func (m *Map) projectObject(obj Object) Object {
+	if m.Orientation != "isometric" {
+		return obj
+	}
+	sx, sy := m.pixelToScreen(obj.X, obj.Y)
+	if obj.Point || obj.GID != 0 || (len(obj.Polygon) == 0 && obj.Width == 0 && obj.Height == 0) {
+		obj.X, obj.Y = sx, sy
+		return obj
+	}
+	outline := obj.Polygon
+	switch {
+	case obj.Ellipse:
+		outline = make([]Vector, 16)
+		for i := range outline {
+			sin, cos := math.Sincos(2 * math.Pi * float64(i) / float64(len(outline)))
+			outline[i] = Vector{obj.Width / 2 * (1 + cos), obj.Height / 2 * (1 + sin)}
+		}
+	case len(outline) == 0:
+		outline = []Vector{{0, 0}, {obj.Width, 0}, {obj.Width, obj.Height}, {0, obj.Height}}
+	}
+	rotation := obj.Rotation * math.Pi / 180
+	projected := make([]Vector, len(outline))
+	for i, pt := range outline {
+		pt = pt.Rotate(rotation)
+		px, py := m.pixelToScreen(obj.X+pt.X, obj.Y+pt.Y)
+		projected[i] = Vector{px - sx, py - sy}
+	}
+	obj.X, obj.Y = sx, sy
+	obj.Polygon = projected
+	obj.Ellipse = false
+	obj.Rotation = 0
+	return obj
+}
+
+func (m *Map) tileOutline() []Vector {
+	tw, th := float64(m.TileWidth), float64(m.TileHeight)
+	if m.Orientation == "hexagonal" {
+		p := m.hexParams()
+		if p.staggerX {
+			return []Vector{{p.sideOffsetX, 0}, {p.columnWidth, 0}, {tw, th / 2}, {p.columnWidth, th}, {p.sideOffsetX, th}, {0, th / 2}}
+		}
+		return []Vector{{tw / 2, 0}, {tw, p.sideOffsetY}, {tw, p.rowHeight}, {tw / 2, th}, {0, p.rowHeight}, {0, p.sideOffsetY}}
+	}
+	if m.Orientation == "isometric" || m.Orientation == "staggered" {
+		return []Vector{{tw / 2, 0}, {tw, th / 2}, {tw / 2, th}, {0, th / 2}}
+	}
+	return []Vector{{0, 0}, {tw, 0}, {tw, th}, {0, th}}
+}
+
+func (m *Map) pixelBounds(tiles image.Rectangle) (float64, float64, float64, float64) {
+	if tiles.Empty() {
+		return 0, 0, 0, 0
+	}
+	if m.isOrthogonal() {
+		tw, th := float64(m.TileWidth), float64(m.TileHeight)
+		return float64(tiles.Min.X) * tw, float64(tiles.Min.Y) * th, float64(tiles.Dx()) * tw, float64(tiles.Dy()) * th
+	}
+	xs := []int{tiles.Min.X, tiles.Min.X + 1, tiles.Max.X - 2, tiles.Max.X - 1}
+	ys := []int{tiles.Min.Y, tiles.Min.Y + 1, tiles.Max.Y - 2, tiles.Max.Y - 1}
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, y := range ys {
+		for _, x := range xs {
+			if !image.Pt(x, y).In(tiles) {
+				continue
+			}
+			px, py := m.tileToPixel(x, y)
+			minX, minY = math.Min(minX, px), math.Min(minY, py)
+			maxX, maxY = math.Max(maxX, px+float64(m.TileWidth)), math.Max(maxY, py+float64(m.TileHeight))
+		}
+	}
+	return minX, minY, maxX - minX, maxY - minY
+}
+
+func (m *Map) visibleTiles(vx, vy, vw, vh float64) image.Rectangle {
+	if m.isOrthogonal() {
+		tw, th := float64(m.TileWidth), float64(m.TileHeight)
+		return image.Rect(int(math.Floor(vx/tw)), int(math.Floor(vy/th)), int(math.Ceil((vx+vw)/tw)), int(math.Ceil((vy+vh)/th)))
+	}
+	var r image.Rectangle
+	for i, c := range [4]Vector{{vx, vy}, {vx + vw, vy}, {vx, vy + vh}, {vx + vw, vy + vh}} {
+		x, y := m.pixelToTile(c.X, c.Y)
+		if i == 0 {
+			r = image.Rect(x, y, x+1, y+1)
+		} else {
+			r = r.Union(image.Rect(x, y, x+1, y+1))
+		}
+	}
+	return r.Inset(-2)
+}
+
+func (m *Map) eachTile(r image.Rectangle, fn func(x, y int)) {
+	stepX, x0, x1 := 1, r.Min.X, r.Max.X
+	stepY, y0, y1 := 1, r.Min.Y, r.Max.Y
+	switch m.RenderOrder {
+	case "left-down", "left-up":
+		stepX, x0, x1 = -1, r.Max.X-1, r.Min.X-1
+	}
+	switch m.RenderOrder {
+	case "right-up", "left-up":
+		stepY, y0, y1 = -1, r.Max.Y-1, r.Min.Y-1
+	}
+	staggerX := m.isStaggered() && m.StaggerAxis == "x"
+	p := m.hexParams()
+	for y := y0; y != y1; y += stepY {
+		if !staggerX {
+			for x := x0; x != x1; x += stepX {
+				fn(x, y)
+			}
+			continue
+		}
+		for _, staggered := range []bool{false, true} {
+			for x := x0; x != x1; x += stepX {
+				if p.doStaggerX(x) == staggered {
+					fn(x, y)
+				}
+			}
+		}
+	}
+}
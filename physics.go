@@ -2,6 +2,8 @@ package gobonsai
 
 import (
 	"image/color"
+	"math"
+	"sort"
 	"sync"
 
 	"github.com/hajimehoshi/ebiten/v2"
@@ -10,19 +12,98 @@ import (
 
 var Gravity float64 = 200
 
+type ContactSide int
+
+const (
+	ContactNone ContactSide = iota
+	ContactLeft
+	ContactRight
+	ContactTop
+	ContactBottom
+)
+
+type Contact struct {
+	Entity  Entity
+	Other   Entity
+	Side    ContactSide
+	ImpactX float64
+	ImpactY float64
+	Enter   bool
+}
+
+func (c Contact) IsGround() bool {
+	return c.Side == ContactBottom
+}
+
+func (c Contact) IsLanding() bool {
+	return c.Side == ContactBottom && c.Enter
+}
+
+func (c Contact) IsWall() bool {
+	return c.Side == ContactLeft || c.Side == ContactRight
+}
+
+func (c Contact) IsCeiling() bool {
+	return c.Side == ContactTop
+}
+
 type PhysicsSystem struct {
-	em *ECSManager
+	em       *ECSManager
+	mu       sync.Mutex
+	contacts map[Entity][]Contact
+	previous map[Entity][]Contact
 }
 
 func NewPhysicsSystem(em *ECSManager) *PhysicsSystem {
-	return &PhysicsSystem{em: em}
+	return &PhysicsSystem{
+		em:       em,
+		contacts: make(map[Entity][]Contact),
+		previous: make(map[Entity][]Contact),
+	}
 }
 
-func (ps *PhysicsSystem) AddCollider(x, y, width, height float64) {
+func (ps *PhysicsSystem) AddCollider(x, y, width, height float64) Entity {
 	entity := Mecs.AddEntity()
 	entity.AddComponent("position", &PositionComponent{X: x, Y: y})
 	entity.AddComponent("size", &SizeComponent{Width: width, Height: height})
 	entity.AddComponent("solid", true)
+	return entity
+}
+
+func (ps *PhysicsSystem) AddOneWayCollider(x, y, width, height float64) Entity {
+	entity := ps.AddCollider(x, y, width, height)
+	entity.AddComponent("oneway", true)
+	return entity
+}
+
+func (ps *PhysicsSystem) AddShapeCollider(x, y float64, collider *ColliderComponent) Entity {
+	pos := &PositionComponent{X: x, Y: y}
+	lo, hi := Vector{math.Inf(1), math.Inf(1)}, Vector{math.Inf(-1), math.Inf(-1)}
+	for _, shape := range colliderShapes(pos, collider) {
+		slo, shi := shape.bounds()
+		lo = Vector{math.Min(lo.X, slo.X), math.Min(lo.Y, slo.Y)}
+		hi = Vector{math.Max(hi.X, shi.X), math.Max(hi.Y, shi.Y)}
+	}
+	entity := Mecs.AddEntity()
+	entity.AddComponent("position", pos)
+	entity.AddComponent("size", &SizeComponent{
+		Width:        hi.X - lo.X,
+		Height:       hi.Y - lo.Y,
+		LeftOffset:   x - lo.X,
+		RightOffset:  lo.X - x,
+		TopOffset:    y - lo.Y,
+		BottomOffset: lo.Y - y,
+	})
+	entity.AddComponent("collider", collider)
+	entity.AddComponent("solid", true)
+	entity.AddComponent("solidshape", true)
+	return entity
+}
+
+func (ps *PhysicsSystem) AddOneWayShapeCollider(x, y float64, collider *ColliderComponent) Entity {
+	entity := ps.AddShapeCollider(x, y, collider)
+	entity.AddComponent("oneway", true)
+	return entity
 }
 
 func (ps *PhysicsSystem) Init() {}
@@ -30,6 +111,10 @@ func (ps *PhysicsSystem) Init() {}
 func (ps *PhysicsSystem) Update(deltaTime float64, args ...interface{}) {
 	entities := ps.em.GetEntitiesWithComponents("position", "velocity", "size")
 
+	ps.mu.Lock()
+	ps.previous, ps.contacts = ps.contacts, make(map[Entity][]Contact)
+	ps.mu.Unlock()
+
 	var wg sync.WaitGroup
 	for _, e := range entities {
 		wg.Add(1)
@@ -39,6 +124,8 @@ func (ps *PhysicsSystem) Update(deltaTime float64, args ...interface{}) {
 		}(e)
 	}
 	wg.Wait()
+
+	ps.dispatchContacts()
 }
 
 func (ps *PhysicsSystem) processEntity(e Entity, deltaTime float64) {
@@ -52,6 +139,7 @@ func (ps *PhysicsSystem) processEntity(e Entity, deltaTime float64) {
 	}
 
 	vel.Y += Gravity * deltaTime
+	impactX, impactY := vel.X, vel.Y
 
 	newX := pos.X + vel.X*deltaTime
 	newY := pos.Y + vel.Y*deltaTime
@@ -74,8 +162,12 @@ func (ps *PhysicsSystem) processEntity(e Entity, deltaTime float64) {
 			continue
 		}
 
-		wouldCollideX := isColliding(newX, pos.Y, size, otherPos, otherSize)
-		wouldCollideY := isColliding(pos.X, newY, size, otherPos, otherSize)
+		wouldCollideX := collidesWith(newX, pos.Y, size, other, otherPos, otherSize)
+		wouldCollideY := collidesWith(pos.X, newY, size, other, otherPos, otherSize)
+		if oneWay, _ := other.GetComponent("oneway").(bool); oneWay {
+			wouldCollideX = false
+			wouldCollideY = wouldCollideY && vel.Y > 0 && isAbove(pos.Y, size, otherPos, otherSize)
+		}
 
 		if wouldCollideX {
 			newX = pos.X
@@ -86,12 +178,102 @@ func (ps *PhysicsSystem) processEntity(e Entity, deltaTime float64) {
 			newY = pos.Y
 			vel.Y = 0
 		}
+
+		if wouldCollideX || wouldCollideY {
+			ps.addContact(e, other, wouldCollideX, wouldCollideY, impactX, impactY)
+		}
 	}
 
 	pos.X = newX
 	pos.Y = newY
 }
 
+func (ps *PhysicsSystem) addContact(e, other Entity, hitX, hitY bool, impactX, impactY float64) {
+	if otherVel, ok := other.GetComponent("velocity").(*VelocityComponent); ok {
+		impactX -= otherVel.X
+		impactY -= otherVel.Y
+	}
+	var contacts []Contact
+	if hitX {
+		side := ContactRight
+		if impactX < 0 {
+			side = ContactLeft
+		}
+		contacts = append(contacts, Contact{Entity: e, Other: other, Side: side, ImpactX: impactX})
+	}
+	if hitY {
+		side := ContactBottom
+		if impactY < 0 {
+			side = ContactTop
+		}
+		contacts = append(contacts, Contact{Entity: e, Other: other, Side: side, ImpactY: impactY})
+	}
+
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	for _, c := range contacts {
+		c.Enter = !hasContact(ps.previous[e], c.Other, c.Side)
+		ps.contacts[e] = append(ps.contacts[e], c)
+	}
+}
+
+func hasContact(contacts []Contact, other Entity, side ContactSide) bool {
+	for _, c := range contacts {
+		if c.Other == other && c.Side == side {
+			return true
+		}
+	}
+	return false
+}
+
+func (ps *PhysicsSystem) dispatchContacts() {
+	ps.mu.Lock()
+	entities := make([]Entity, 0, len(ps.contacts))
+	for e := range ps.contacts {
+		entities = append(entities, e)
+	}
+	ps.mu.Unlock()
+
+	sort.Slice(entities, func(i, j int) bool {
+		return entities[i] < entities[j]
+	})
+	for _, e := range entities {
+		callback, ok := e.GetComponent("contact").(func(Contact))
+		if !ok {
+			continue
+		}
+		for _, c := range ps.GetContacts(e) {
+			callback(c)
+		}
+	}
+}
+
+func (ps *PhysicsSystem) GetContacts(e Entity) []Contact {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	contacts := make([]Contact, len(ps.contacts[e]))
+	copy(contacts, ps.contacts[e])
+	return contacts
+}
+
+func (ps *PhysicsSystem) IsGrounded(e Entity) bool {
+	for _, c := range ps.GetContacts(e) {
+		if c.IsGround() {
+			return true
+		}
+	}
+	return false
+}
+
+func (ps *PhysicsSystem) IsTouchingWall(e Entity) bool {
+	for _, c := range ps.GetContacts(e) {
+		if c.IsWall() {
+			return true
+		}
+	}
+	return false
+}
+
 func containsEntity(entities []Entity, entity Entity) bool {
 	for _, e := range entities {
 		if e == entity {
@@ -115,6 +297,29 @@ func isColliding(x1, y1 float64, size1 *SizeComponent, pos2 *PositionComponent,
 	return x1Min < x2Max && x1Max > x2Min && y1Min < y2Max && y1Max > y2Min
 }
 
+func collidesWith(x, y float64, size *SizeComponent, other Entity, otherPos *PositionComponent, otherSize *SizeComponent) bool {
+	if !isColliding(x, y, size, otherPos, otherSize) {
+		return false
+	}
+	collider, ok := other.GetComponent("collider").(*ColliderComponent)
+	if shaped, _ := other.GetComponent("solidshape").(bool); !shaped || !ok {
+		return true
+	}
+	left, top := x-size.LeftOffset, y-size.TopOffset
+	right, bottom := x+size.Width+size.RightOffset, y+size.Height+size.BottomOffset
+	box := convexShape{points: []Vector{{left, top}, {right, top}, {right, bottom}, {left, bottom}}}
+	for _, shape := range colliderShapes(otherPos, collider) {
+		if _, _, hit := collideShapes(box, shape); hit {
+			return true
+		}
+	}
+	return false
+}
+
+func isAbove(y1 float64, size1 *SizeComponent, pos2 *PositionComponent, size2 *SizeComponent) bool {
+	return y1+size1.Height+size1.BottomOffset <= pos2.Y-size2.TopOffset+0.01
+}
+
 func (ps *PhysicsSystem) CheckIfColliding(entity Entity, newx, newy float64) bool {
 	pos, ok1 := entity.GetComponent("position").(*PositionComponent)
 	size, ok2 := entity.GetComponent("size").(*SizeComponent)
@@ -137,8 +342,12 @@ func (ps *PhysicsSystem) CheckIfColliding(entity Entity, newx, newy float64) boo
 			continue
 		}
 
-		wouldCollideX := isColliding(newx, pos.Y, size, otherPos, otherSize)
-		wouldCollideY := isColliding(pos.X, newy, size, otherPos, otherSize)
+		wouldCollideX := collidesWith(newx, pos.Y, size, other, otherPos, otherSize)
+		wouldCollideY := collidesWith(pos.X, newy, size, other, otherPos, otherSize)
+		if oneWay, _ := other.GetComponent("oneway").(bool); oneWay {
+			wouldCollideX = false
+			wouldCollideY = wouldCollideY && newy > pos.Y && isAbove(pos.Y, size, otherPos, otherSize)
+		}
 
 		if wouldCollideX || wouldCollideY {
 			return true
@@ -0,0 +1,188 @@
+package gobonsai
+
+import (
+	"fmt"
+	"image/color"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type Properties map[string]Property
+
+func NewProperties(list []Property) Properties {
+	props := make(Properties, len(list))
+	for _, prop := range list {
+		props[prop.Name] = normalizeProperty(prop)
+	}
+	return props
+}
+
+func (m *Map) Props() Properties {
+	return NewProperties(m.RawProps)
+}
+
+func (ts *Tileset) Props() Properties {
+	return NewProperties(ts.RawProps)
+}
+
+func (t *TilesetTile) Props() Properties {
+	return NewProperties(t.Properties)
+}
+
+func setPropertyList(list []Property, name string, value interface{}) []Property {
+	for i := range list {
+		if list[i].Name == name {
+			list[i].Value = value
+			return list
+		}
+	}
+	return append(list, Property{Name: name, Value: value})
+}
+
+func mergePropertyList(base, over []Property) []Property {
+	merged := append([]Property(nil), base...)
+	for _, prop := range over {
+		i := slices.IndexFunc(merged, func(p Property) bool { return p.Name == prop.Name })
+		if i < 0 {
+			merged = append(merged, prop)
+			continue
+		}
+		if class, ok := normalizeProperty(merged[i]).Value.(Properties); ok {
+			if over, ok := normalizeProperty(prop).Value.(Properties); ok {
+				prop.Value = class.Merge(over)
+			}
+		}
+		merged[i] = prop
+	}
+	return merged
+}
+
+func normalizeProperty(prop Property) Property {
+	members, ok := prop.Value.(map[string]interface{})
+	if !ok {
+		return prop
+	}
+	class := make(Properties, len(members))
+	for name, value := range members {
+		class[name] = normalizeProperty(Property{Name: name, Value: value})
+	}
+	if prop.Type == "" {
+		prop.Type = "class"
+	}
+	prop.Value = class
+	return prop
+}
+
+func (p Properties) Names() []string {
+	names := make([]string, 0, len(p))
+	for name := range p {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (p Properties) Has(name string) bool {
+	_, ok := p[name]
+	return ok
+}
+
+func (p Properties) Get(name string) interface{} {
+	if prop, ok := p[name]; ok {
+		return prop.Value
+	}
+	return nil
+}
+
+func (p Properties) GetString(name string) string {
+	switch v := p.Get(name).(type) {
+	case string:
+		return v
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func (p Properties) GetFloat(name string) float64 {
+	switch v := p.Get(name).(type) {
+	case float64:
+		return v
+	case int:
+		return float64(v)
+	case string:
+		f, _ := strconv.ParseFloat(v, 64)
+		return f
+	default:
+		return 0
+	}
+}
+
+func (p Properties) GetInt(name string) int {
+	return int(p.GetFloat(name))
+}
+
+func (p Properties) GetBool(name string) bool {
+	switch v := p.Get(name).(type) {
+	case bool:
+		return v
+	case string:
+		b, _ := strconv.ParseBool(v)
+		return b
+	default:
+		return false
+	}
+}
+
+func (p Properties) GetColor(name string) color.NRGBA {
+	c, _ := parseTiledColor(p.GetString(name))
+	return c
+}
+
+func (p Properties) GetFile(name string) string {
+	return strings.ReplaceAll(p.GetString(name), "\\", "/")
+}
+
+func (p Properties) GetObjectRef(name string) int {
+	return p.GetInt(name)
+}
+
+func (p Properties) GetClass(name string) Properties {
+	class, _ := p.Get(name).(Properties)
+	return class
+}
+
+func (p Properties) Merge(over Properties) Properties {
+	merged := make(Properties, len(p)+len(over))
+	for name, prop := range p {
+		merged[name] = prop
+	}
+	for name, prop := range over {
+		if base, ok := merged[name].Value.(Properties); ok {
+			if class, ok := prop.Value.(Properties); ok {
+				prop.Value = base.Merge(class)
+			}
+		}
+		merged[name] = prop
+	}
+	return merged
+}
+
+func parseTiledColor(s string) (color.NRGBA, error) {
+	s = strings.TrimPrefix(s, "#")
+	v, err := strconv.ParseUint(s, 16, 32)
+	if err != nil {
+		return color.NRGBA{}, fmt.Errorf("invalid color %q: %w", s, err)
+	}
+	switch len(s) {
+	case 6:
+		return color.NRGBA{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v), A: 0xff}, nil
+	case 8:
+		return color.NRGBA{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v), A: uint8(v >> 24)}, nil
+	default:
+		return color.NRGBA{}, fmt.Errorf("invalid color %q", s)
+	}
+}
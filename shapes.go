@@ -0,0 +1,420 @@
+package gobonsai
+
+import (
+	"image/color"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+const shapeEpsilon = 1e-9
+
+type ShapeType int
+
+const (
+	ShapeRect ShapeType = iota
+	ShapeCircle
+	ShapeCapsule
+	ShapeBox
+	ShapePolygon
+)
+
+type Vector struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+func (v Vector) Add(o Vector) Vector {
+	return Vector{v.X + o.X, v.Y + o.Y}
+}
+
+func (v Vector) Sub(o Vector) Vector {
+	return Vector{v.X - o.X, v.Y - o.Y}
+}
+
+func (v Vector) Scale(s float64) Vector {
+	return Vector{v.X * s, v.Y * s}
+}
+
+func (v Vector) Dot(o Vector) float64 {
+	return v.X*o.X + v.Y*o.Y
+}
+
+func (v Vector) Len() float64 {
+	return math.Hypot(v.X, v.Y)
+}
+
+func (v Vector) Normalize() Vector {
+	l := v.Len()
+	if l < shapeEpsilon {
+		return Vector{}
+	}
+	return Vector{v.X / l, v.Y / l}
+}
+
+func (v Vector) Perp() Vector {
+	return Vector{-v.Y, v.X}
+}
+
+func (v Vector) Rotate(angle float64) Vector {
+	sin, cos := math.Sincos(angle)
+	return Vector{v.X*cos - v.Y*sin, v.X*sin + v.Y*cos}
+}
+
+type convexShape struct {
+	points []Vector
+	radius float64
+}
+
+type ColliderPart struct {
+	Shape    ShapeType
+	OffsetX  float64
+	OffsetY  float64
+	Width    float64
+	Height   float64
+	Radius   float64
+	Rotation float64
+	Points   []Vector
+}
+
+func colliderShapes(p *PositionComponent, c *ColliderComponent) []convexShape {
+	shapes, _ := appendColliderShapes(nil, nil, p, c)
+	return shapes
+}
+
+func appendColliderShapes(shapes []convexShape, points []Vector, p *PositionComponent, c *ColliderComponent) ([]convexShape, []Vector) {
+	origin := Vector{p.X + c.OffsetX, p.Y + c.OffsetY}
+	var shape convexShape
+	switch {
+	case len(c.Parts) > 0:
+		for _, part := range c.Parts {
+			points, shape = appendPartShape(points, origin, part)
+			shapes = append(shapes, shape)
+		}
+	case c.CrossShape:
+		points, shape = appendPartShape(points, origin, ColliderPart{OffsetX: -c.HorizWidth / 2, OffsetY: -c.HorizHeight / 2, Width: c.HorizWidth, Height: c.HorizHeight})
+		shapes = append(shapes, shape)
+		points, shape = appendPartShape(points, origin, ColliderPart{OffsetX: -c.VertWidth / 2, OffsetY: -c.VertHeight / 2, Width: c.VertWidth, Height: c.VertHeight})
+		shapes = append(shapes, shape)
+	default:
+		points, shape = appendPartShape(points, origin, ColliderPart{
+			Shape:    c.Shape,
+			Width:    c.Width,
+			Height:   c.Height,
+			Radius:   c.Radius,
+			Rotation: c.Rotation,
+			Points:   c.Points,
+		})
+		shapes = append(shapes, shape)
+	}
+	return shapes, points
+}
+
+func appendPartShape(points []Vector, origin Vector, part ColliderPart) ([]Vector, convexShape) {
+	start := len(points)
+	radius := 0.0
+	origin = origin.Add(Vector{part.OffsetX, part.OffsetY})
+	switch part.Shape {
+	case ShapeCircle:
+		points = append(points, origin)
+		radius = part.Radius
+	case ShapeCapsule:
+		center := origin.Add(Vector{part.Width / 2, part.Height / 2})
+		var a, b Vector
+		if part.Height >= part.Width {
+			radius = part.Width / 2
+			a, b = Vector{0, -part.Height/2 + radius}, Vector{0, part.Height/2 - radius}
+		} else {
+			radius = part.Height / 2
+			a, b = Vector{-part.Width/2 + radius, 0}, Vector{part.Width/2 - radius, 0}
+		}
+		points = append(points, center.Add(a.Rotate(part.Rotation)), center.Add(b.Rotate(part.Rotation)))
+	case ShapeBox:
+		center := origin.Add(Vector{part.Width / 2, part.Height / 2})
+		hw, hh := part.Width/2, part.Height/2
+		points = append(points,
+			center.Add(Vector{-hw, -hh}.Rotate(part.Rotation)),
+			center.Add(Vector{hw, -hh}.Rotate(part.Rotation)),
+			center.Add(Vector{hw, hh}.Rotate(part.Rotation)),
+			center.Add(Vector{-hw, hh}.Rotate(part.Rotation)))
+	case ShapePolygon:
+		for _, pt := range part.Points {
+			points = append(points, origin.Add(pt.Rotate(part.Rotation)))
+		}
+	default:
+		x, y, w, h := origin.X, origin.Y, part.Width, part.Height
+		points = append(points, Vector{x, y}, Vector{x + w, y}, Vector{x + w, y + h}, Vector{x, y + h})
+	}
+	return points, convexShape{points: points[start:len(points):len(points)], radius: radius}
+}
+
+func (s convexShape) bounds() (Vector, Vector) {
+	lo, hi := Vector{math.Inf(1), math.Inf(1)}, Vector{math.Inf(-1), math.Inf(-1)}
+	for _, pt := range s.points {
+		lo = Vector{math.Min(lo.X, pt.X), math.Min(lo.Y, pt.Y)}
+		hi = Vector{math.Max(hi.X, pt.X), math.Max(hi.Y, pt.Y)}
+	}
+	return lo.Sub(Vector{s.radius, s.radius}), hi.Add(Vector{s.radius, s.radius})
+}
+
+func (s convexShape) center() Vector {
+	var sum Vector
+	for _, pt := range s.points {
+		sum = sum.Add(pt)
+	}
+	return sum.Scale(1 / float64(len(s.points)))
+}
+
+func (s convexShape) project(axis Vector) (float64, float64) {
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, pt := range s.points {
+		d := pt.Dot(axis)
+		lo = math.Min(lo, d)
+		hi = math.Max(hi, d)
+	}
+	return lo - s.radius, hi + s.radius
+}
+
+func (s convexShape) contains(pt Vector) bool {
+	switch len(s.points) {
+	case 0:
+		return false
+	case 1, 2:
+		closest := closestPointOnSegment(pt, s.points[0], s.points[len(s.points)-1])
+		return pt.Sub(closest).Len() <= s.radius
+	}
+	sign := 0.0
+	for i, a := range s.points {
+		b := s.points[(i+1)%len(s.points)]
+		cross := (b.X-a.X)*(pt.Y-a.Y) - (b.Y-a.Y)*(pt.X-a.X)
+		if cross == 0 {
+			continue
+		}
+		if sign != 0 && cross*sign < 0 {
+			return false
+		}
+		sign = cross
+	}
+	return true
+}
+
+func (s convexShape) separated(other convexShape, normal *Vector, depth *float64) bool {
+	test := func(axis Vector) bool {
+		if axis == (Vector{}) {
+			return false
+		}
+		minA, maxA := s.project(axis)
+		minB, maxB := other.project(axis)
+		overlap := math.Min(maxA, maxB) - math.Max(minA, minB)
+		if overlap <= 0 {
+			return true
+		}
+		if overlap < *depth {
+			*normal, *depth = axis, overlap
+		}
+		return false
+	}
+	switch len(s.points) {
+	case 1:
+		for _, pt := range other.points {
+			if test(pt.Sub(s.points[0]).Normalize()) {
+				return true
+			}
+		}
+	case 2:
+		if test(s.points[1].Sub(s.points[0]).Perp().Normalize()) {
+			return true
+		}
+		for _, pt := range other.points {
+			closest := closestPointOnSegment(pt, s.points[0], s.points[1])
+			if test(pt.Sub(closest).Normalize()) {
+				return true
+			}
+		}
+	default:
+		for i, pt := range s.points {
+			next := s.points[(i+1)%len(s.points)]
+			if test(next.Sub(pt).Perp().Normalize()) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func collideShapes(a, b convexShape) (Vector, float64, bool) {
+	if len(a.points) == 0 || len(b.points) == 0 {
+		return Vector{}, 0, false
+	}
+	if len(a.points) <= 2 && len(b.points) <= 2 {
+		return collideRounded(a, b)
+	}
+	normal, depth := Vector{}, math.Inf(1)
+	if a.separated(b, &normal, &depth) || b.separated(a, &normal, &depth) {
+		return Vector{}, 0, false
+	}
+	if math.IsInf(depth, 1) {
+		return Vector{}, 0, false
+	}
+	if normal.Dot(b.center().Sub(a.center())) < 0 {
+		normal = normal.Scale(-1)
+	}
+	return normal, depth, true
+}
+
+func collideRounded(a, b convexShape) (Vector, float64, bool) {
+	a0, a1 := a.points[0], a.points[len(a.points)-1]
+	b0, b1 := b.points[0], b.points[len(b.points)-1]
+	pa, pb := closestSegmentPoints(a0, a1, b0, b1)
+	diff := pb.Sub(pa)
+	dist := diff.Len()
+	if dist >= a.radius+b.radius {
+		return Vector{}, 0, false
+	}
+	normal := diff.Normalize()
+	if normal == (Vector{}) {
+		normal = b.center().Sub(a.center()).Normalize()
+	}
+	if normal == (Vector{}) {
+		normal = Vector{0, 1}
+	}
+	return normal, a.radius + b.radius - dist, true
+}
+
+func closestPointOnSegment(p, a, b Vector) Vector {
+	ab := b.Sub(a)
+	l := ab.Dot(ab)
+	if l < shapeEpsilon {
+		return a
+	}
+	t := math.Max(0, math.Min(1, p.Sub(a).Dot(ab)/l))
+	return a.Add(ab.Scale(t))
+}
+
+func closestSegmentPoints(p1, q1, p2, q2 Vector) (Vector, Vector) {
+	d1, d2, r := q1.Sub(p1), q2.Sub(p2), p1.Sub(p2)
+	a, e, f := d1.Dot(d1), d2.Dot(d2), d2.Dot(r)
+	clamp := func(v float64) float64 { return math.Max(0, math.Min(1, v)) }
+	var s, t float64
+	switch {
+	case a < shapeEpsilon && e < shapeEpsilon:
+		return p1, p2
+	case a < shapeEpsilon:
+		t = clamp(f / e)
+	case e < shapeEpsilon:
+		s = clamp(-d1.Dot(r) / a)
+	default:
+		c, b := d1.Dot(r), d1.Dot(d2)
+		if denom := a*e - b*b; denom != 0 {
+			s = clamp((b*f - c*e) / denom)
+		}
+		t = (b*s + f) / e
+		if t < 0 {
+			t, s = 0, clamp(-c/a)
+		} else if t > 1 {
+			t, s = 1, clamp((b-c)/a)
+		}
+	}
+	return p1.Add(d1.Scale(s)), p2.Add(d2.Scale(t))
+}
+
+func isConvex(points []Vector) bool {
+	sign := 0.0
+	for i, pt := range points {
+		a, b := points[(i+1)%len(points)], points[(i+2)%len(points)]
+		cross := (a.X-pt.X)*(b.Y-a.Y) - (a.Y-pt.Y)*(b.X-a.X)
+		if math.Abs(cross) < shapeEpsilon {
+			continue
+		}
+		if sign != 0 && cross*sign < 0 {
+			return false
+		}
+		sign = cross
+	}
+	return true
+}
+
+func triangulate(points []Vector) [][]Vector {
+	area := 0.0
+	for i, pt := range points {
+		next := points[(i+1)%len(points)]
+		area += pt.X*next.Y - next.X*pt.Y
+	}
+	orient := 1.0
+	if area < 0 {
+		orient = -1
+	}
+	cross := func(o, a, b Vector) float64 {
+		return ((a.X-o.X)*(b.Y-o.Y) - (a.Y-o.Y)*(b.X-o.X)) * orient
+	}
+	inside := func(p, a, b, c Vector) bool {
+		return cross(a, b, p) >= 0 && cross(b, c, p) >= 0 && cross(c, a, p) >= 0
+	}
+
+	remaining := make([]Vector, len(points))
+	copy(remaining, points)
+	var triangles [][]Vector
+	for guard := 0; len(remaining) > 3 && guard < len(points)*len(points); guard++ {
+		clipped := false
+		for i := range remaining {
+			prev := remaining[(i+len(remaining)-1)%len(remaining)]
+			cur := remaining[i]
+			next := remaining[(i+1)%len(remaining)]
+			if cross(prev, cur, next) <= 0 {
+				continue
+			}
+			ear := true
+			for _, pt := range remaining {
+				if pt != prev && pt != cur && pt != next && inside(pt, prev, cur, next) {
+					ear = false
+					break
+				}
+			}
+			if ear {
+				triangles = append(triangles, []Vector{prev, cur, next})
+				remaining = append(remaining[:i:i], remaining[i+1:]...)
+				clipped = true
+				break
+			}
+		}
+		if !clipped {
+			break
+		}
+	}
+	if len(remaining) == 3 {
+		triangles = append(triangles, remaining)
+	}
+	return triangles
+}
+
+func drawShape(screen *ebiten.Image, s convexShape, col color.RGBA, view ebiten.GeoM) {
+	points := make([]Vector, len(s.points))
+	for i, pt := range s.points {
+		x, y := view.Apply(pt.X, pt.Y)
+		points[i] = Vector{x, y}
+	}
+	ox, oy := view.Apply(0, 0)
+	ux, uy := view.Apply(1, 0)
+	radius := float32(s.radius * math.Hypot(ux-ox, uy-oy))
+
+	switch len(points) {
+	case 0:
+		return
+	case 1:
+		vector.StrokeCircle(screen, float32(points[0].X), float32(points[0].Y), radius, 1, col, false)
+	case 2:
+		a, b := points[0], points[1]
+		n := b.Sub(a).Perp().Normalize().Scale(float64(radius))
+		vector.StrokeCircle(screen, float32(a.X), float32(a.Y), radius, 1, col, false)
+		vector.StrokeCircle(screen, float32(b.X), float32(b.Y), radius, 1, col, false)
+		for _, side := range []Vector{n, n.Scale(-1)} {
+			vector.StrokeLine(screen, float32(a.X+side.X), float32(a.Y+side.Y), float32(b.X+side.X), float32(b.Y+side.Y), 1, col, false)
+		}
+	default:
+		for i, pt := range points {
+			next := points[(i+1)%len(points)]
+			vector.StrokeLine(screen, float32(pt.X), float32(pt.Y), float32(next.X), float32(next.Y), 1, col, false)
+		}
+	}
+}
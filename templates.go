@@ -0,0 +1,172 @@
+package gobonsai
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+type objectTemplate struct {
+	object   Object
+	firstGID int
+	tileset  string
+}
+
+type tmxTemplate struct {
+	Tileset *struct {
+		FirstGID int    `xml:"firstgid,attr"`
+		Source   string `xml:"source,attr"`
+	} `xml:"tileset"`
+	Object tmxObject `xml:"object"`
+}
+
+type jsonTemplate struct {
+	Tileset *struct {
+		FirstGID int    `json:"firstgid"`
+		Source   string `json:"source"`
+	} `json:"tileset"`
+	Object Object `json:"object"`
+}
+
+func (tmm *TilemapsManager) loadTemplate(path string, cache map[string]objectTemplate) (objectTemplate, error) {
+	if tpl, ok := cache[path]; ok {
+		return tpl, nil
+	}
+	data, err := tmm.loadFile(path)
+	if err != nil {
+		return objectTemplate{}, fmt.Errorf("failed to load template (%s): %w", path, err)
+	}
+	var tpl objectTemplate
+	var source string
+	if strings.EqualFold(filepath.Ext(path), ".tx") {
+		var raw tmxTemplate
+		if err := xml.Unmarshal(data, &raw); err != nil {
+			return objectTemplate{}, fmt.Errorf("failed to parse template (%s): %w", path, err)
+		}
+		tpl.object = raw.Object.toObject()
+		if raw.Tileset != nil {
+			tpl.firstGID, source = raw.Tileset.FirstGID, raw.Tileset.Source
+		}
+	} else {
+		var raw jsonTemplate
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return objectTemplate{}, fmt.Errorf("failed to parse template (%s): %w", path, err)
+		}
+		tpl.object = raw.Object
+		if raw.Tileset != nil {
+			tpl.firstGID, source = raw.Tileset.FirstGID, raw.Tileset.Source
+		}
+	}
+	if source != "" {
+		tpl.tileset = tmm.normalizeJSONPath(filepath.Join(filepath.Dir(path), source))
+	}
+	cache[path] = tpl
+	return tpl, nil
+}
+
+func (tmm *TilemapsManager) resolveObjects(tm *TileMap, baseDir string) error {
+	sources := make(map[string]int)
+	for _, emb := range tm.Map.Tilesets {
+		if emb.Source != "" {
+			sources[tmm.normalizeJSONPath(filepath.Join(baseDir, emb.Source))] = emb.FirstGID
+		}
+	}
+	cache := make(map[string]objectTemplate)
+	var resolve func(layers []Layer) error
+	resolve = func(layers []Layer) error {
+		for i := range layers {
+			for j := range layers[i].Objects {
+				obj := &layers[i].Objects[j]
+				if obj.Template != "" {
+					tpl, err := tmm.loadTemplate(tmm.normalizeJSONPath(filepath.Join(baseDir, obj.Template)), cache)
+					if err != nil {
+						return err
+					}
+					base := tpl.object
+					if base.GID != 0 && tpl.tileset != "" {
+						firstGID, ok := sources[tpl.tileset]
+						if !ok {
+							return fmt.Errorf("template %s uses tileset %s missing from map", obj.Template, tpl.tileset)
+						}
+						gid, flags := DecodeGID(base.GID)
+						base.GID = EncodeGID(gid-tpl.firstGID+firstGID, flags)
+					}
+					*obj = applyTemplate(base, *obj)
+				}
+				if obj.GID != 0 {
+					tm.inheritTileProperties(obj)
+				}
+			}
+			if err := resolve(layers[i].Layers); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	err := resolve(tm.Map.Layers)
+	for path := range cache {
+		tm.sources = append(tm.sources, path)
+	}
+	return err
+}
+
+func applyTemplate(base, obj Object) Object {
+	out := base
+	out.ID, out.X, out.Y, out.Template = obj.ID, obj.X, obj.Y, obj.Template
+	if obj.Name != "" {
+		out.Name = obj.Name
+	}
+	if obj.Type != "" {
+		out.Type = obj.Type
+	}
+	if obj.Width != 0 {
+		out.Width = obj.Width
+	}
+	if obj.Height != 0 {
+		out.Height = obj.Height
+	}
+	if obj.Rotation != 0 {
+		out.Rotation = obj.Rotation
+	}
+	if obj.GID != 0 {
+		out.GID = obj.GID
+	}
+	if len(obj.Polygon) > 0 {
+		out.Polygon = obj.Polygon
+	}
+	out.Visible = base.Visible && obj.Visible
+	out.RawProps = mergePropertyList(base.RawProps, obj.RawProps)
+	return out
+}
+
+func (tm *TileMap) tilesetTile(gid int) *TilesetTile {
+	var tileset *Tileset
+	for _, ts := range tm.Tilesets {
+		if ts.FirstGID <= gid && (tileset == nil || ts.FirstGID > tileset.FirstGID) {
+			tileset = ts
+		}
+	}
+	if tileset == nil {
+		return nil
+	}
+	for i := range tileset.Tiles {
+		if tileset.Tiles[i].ID == gid-tileset.FirstGID {
+			return &tileset.Tiles[i]
+		}
+	}
+	return nil
+}
+
+func (tm *TileMap) inheritTileProperties(obj *Object) {
+	gid, _ := DecodeGID(obj.GID)
+	tile := tm.tilesetTile(gid)
+	if tile == nil {
+		return
+	}
+	obj.RawProps = mergePropertyList(tile.Properties, obj.RawProps)
+	if obj.Type == "" {
+		obj.Type = tile.Type
+	}
+}
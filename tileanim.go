@@ -0,0 +1,123 @@
+package gobonsai
+
+import (
+	"fmt"
+	"math"
+)
+
+type TileAnimation struct {
+	Offset float64
+	Speed  float64
+	Paused bool
+
+	elapsed float64
+}
+
+type tileInstance struct {
+	layer *Layer
+	x, y  int
+}
+
+func (info *AnimationInfo) duration() float64 {
+	total := 0.0
+	for _, f := range info.Frames {
+		total += float64(f.Duration)
+	}
+	return total
+}
+
+func (info *AnimationInfo) frameAt(t float64) (int, float64) {
+	total := info.duration()
+	if total <= 0 {
+		return 0, 0
+	}
+	t = math.Mod(t, total)
+	if t < 0 {
+		t += total
+	}
+	for i, f := range info.Frames {
+		if t < float64(f.Duration) {
+			return i, t
+		}
+		t -= float64(f.Duration)
+	}
+	return len(info.Frames) - 1, 0
+}
+
+func (tm *TileMap) updateAnimations(ms float64) {
+	tm.animClock += ms
+	for _, tile := range tm.Tiles {
+		if tile.AnimationInfo != nil {
+			tile.AnimationInfo.CurrentFrame, tile.AnimationInfo.ElapsedTime = tile.AnimationInfo.frameAt(tm.animClock)
+		}
+	}
+	for _, anim := range tm.tileAnims {
+		if !anim.Paused {
+			anim.elapsed += ms * anim.Speed
+		}
+	}
+}
+
+func (tm *TileMap) instanceGID(layer *Layer, gid, x, y int) int {
+	tile, ok := tm.Tiles[gid]
+	if !ok || tile.AnimationInfo == nil {
+		return gid
+	}
+	anim, ok := tm.tileAnims[tileInstance{layer, x, y}]
+	if !ok {
+		return tm.resolveGID(gid)
+	}
+	frame, _ := tile.AnimationInfo.frameAt(anim.elapsed + anim.Offset)
+	return tile.AnimationInfo.BaseGID + tile.AnimationInfo.Frames[frame].TileID
+}
+
+func (tmm *TilemapsManager) SetTileAnimationSpeed(speed float64) {
+	tmm.mu.Lock()
+	defer tmm.mu.Unlock()
+	tmm.animSpeed = speed
+}
+
+func (tmm *TilemapsManager) PauseTileAnimations(paused bool) {
+	tmm.mu.Lock()
+	defer tmm.mu.Unlock()
+	tmm.animPaused = paused
+}
+
+func (tmm *TilemapsManager) SetTileAnimation(layer string, x, y int, anim TileAnimation) error {
+	tmm.mu.Lock()
+	defer tmm.mu.Unlock()
+	tm, ok := tmm.currentMap()
+	if !ok {
+		return fmt.Errorf("no tilemap set")
+	}
+	l := tm.findLayer(layer)
+	if l == nil || l.Type != "tilelayer" {
+		return fmt.Errorf("tile layer %s not found", layer)
+	}
+	if anim.Speed == 0 {
+		anim.Speed = 1
+	}
+	key := tileInstance{l, x, y}
+	if prev, ok := tm.tileAnims[key]; ok {
+		anim.elapsed = prev.elapsed
+	} else {
+		anim.elapsed = tm.animClock
+	}
+	if tm.tileAnims == nil {
+		tm.tileAnims = make(map[tileInstance]*TileAnimation)
+	}
+	tm.tileAnims[key] = &anim
+	return nil
+}
+
+func (tmm *TilemapsManager) ClearTileAnimation(layer string, x, y int) {
+	tmm.mu.Lock()
+	defer tmm.mu.Unlock()
+	tm, ok := tmm.currentMap()
+	if !ok {
+		return
+	}
+	if l := tm.findLayer(layer); l != nil {
+		delete(tm.tileAnims, tileInstance{l, x, y})
+	}
+}
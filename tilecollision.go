@@ -0,0 +1,280 @@
+package gobonsai
+
+import (
+	"image"
+)
+
+type tileSolid int
+
+const (
+	tileOpen tileSolid = iota
+	tileSolidFull
+	tileOneWay
+)
+
+type tileCollider struct {
+	entity Entity
+	cells  image.Rectangle
+}
+
+func (tmm *TilemapsManager) buildTileColliders(tm *TileMap, scale float64) {
+	tm.colliders = make(map[*Layer][]tileCollider)
+	var walk func(layers []Layer)
+	walk = func(layers []Layer) {
+		for i := range layers {
+			if layers[i].Type == "tilelayer" {
+				tmm.buildLayerColliders(tm, &layers[i], layers[i].tileBounds(), scale)
+			}
+			walk(layers[i].Layers)
+		}
+	}
+	walk(tm.Map.Layers)
+}
+
+func (tm *TileMap) addLayerCollider(layer *Layer, entity Entity, cells image.Rectangle) {
+	if tm.colliders == nil {
+		tm.colliders = make(map[*Layer][]tileCollider)
+	}
+	tm.colliders[layer] = append(tm.colliders[layer], tileCollider{entity, cells})
+}
+
+func (tmm *TilemapsManager) rebuildLayerColliders(tm *TileMap, layer *Layer, region image.Rectangle) {
+	colliders := tm.colliders[layer]
+	for grown := true; grown; {
+		grown = false
+		for _, c := range colliders {
+			if c.cells.Overlaps(region) && !c.cells.In(region) {
+				region, grown = region.Union(c.cells), true
+			}
+		}
+	}
+	kept := colliders[:0]
+	for _, c := range colliders {
+		if c.cells.Overlaps(region) {
+			Mecs.RemoveEntity(c.entity)
+		} else {
+			kept = append(kept, c)
+		}
+	}
+	if len(colliders) > 0 {
+		tm.colliders[layer] = kept
+	}
+	tmm.buildLayerColliders(tm, layer, region, tmm.scale)
+}
+
+func (tmm *TilemapsManager) buildLayerColliders(tm *TileMap, layer *Layer, region image.Rectangle, scale float64) {
+	bounds := region.Intersect(layer.tileBounds())
+	if bounds.Empty() {
+		return
+	}
+	if !tm.Map.isOrthogonal() {
+		tmm.buildProjectedLayerColliders(tm, layer, bounds, scale)
+		return
+	}
+	tw, th := float64(tm.Map.TileWidth), float64(tm.Map.TileHeight)
+	cells := make([]tileSolid, bounds.Dx()*bounds.Dy())
+	count := 0
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			gid, flags := DecodeGID(layer.gidAt(x, y))
+			tile, ok := tm.Tiles[gid]
+			if gid == 0 || !ok {
+				continue
+			}
+			idx := (y-bounds.Min.Y)*bounds.Dx() + (x - bounds.Min.X)
+			cell := image.Rect(x, y, x+1, y+1)
+			switch {
+			case tileFlag(tile.Properties, "solid"):
+				cells[idx] = tileSolidFull
+			case tileFlag(tile.Properties, "oneway"):
+				cells[idx] = tileOneWay
+			}
+			iw, ih := tw, th
+			if img, ok := tm.CachedTiles[gid]; ok {
+				iw, ih = float64(img.Bounds().Dx()), float64(img.Bounds().Dy())
+			}
+			offset := tm.tileOffsets[gid]
+			originX := tm.WorldX + float64(x)*tw + float64(offset.X)
+			originY := tm.WorldY + float64(y)*th + th - ih + float64(offset.Y)
+			aligned := iw == tw && ih == th && offset == image.Point{}
+			for _, obj := range tile.Collision {
+				obj = tm.flipTileObject(obj, iw, ih, flags)
+				oneWay := tileFlag(tile.Properties, "oneway") || obj.Type == "oneway" || obj.GetProperty("oneway") == true
+				switch {
+				case obj.Point:
+				case !isRectObject(obj):
+					tm.addLayerCollider(layer, tmm.addShapeCollider(obj, originX, originY, scale, colliderGroup(obj, layer.Props(), "tile"), oneWay), cell)
+					count++
+				case aligned && obj.X == 0 && obj.Y == 0 && obj.Width == tw && obj.Height == th:
+					if cells[idx] == tileOpen {
+						cells[idx] = tileSolidFull
+						if oneWay {
+							cells[idx] = tileOneWay
+						}
+					}
+				case oneWay:
+					tm.addLayerCollider(layer, Mphysics.AddOneWayCollider((originX+obj.X)*scale, (originY+obj.Y)*scale, obj.Width*scale, obj.Height*scale), cell)
+					count++
+				default:
+					tm.addLayerCollider(layer, Mphysics.AddCollider((originX+obj.X)*scale, (originY+obj.Y)*scale, obj.Width*scale, obj.Height*scale), cell)
+					count++
+				}
+			}
+		}
+	}
+
+	for _, r := range mergeTileRects(cells, bounds, tileSolidFull, false) {
+		tm.addLayerCollider(layer, Mphysics.AddCollider((tm.WorldX+float64(r.Min.X)*tw)*scale, (tm.WorldY+float64(r.Min.Y)*th)*scale, float64(r.Dx())*tw*scale, float64(r.Dy())*th*scale), r)
+		count++
+	}
+	for _, r := range mergeTileRects(cells, bounds, tileOneWay, true) {
+		tm.addLayerCollider(layer, Mphysics.AddOneWayCollider((tm.WorldX+float64(r.Min.X)*tw)*scale, (tm.WorldY+float64(r.Min.Y)*th)*scale, float64(r.Dx())*tw*scale, float64(r.Dy())*th*scale), r)
+		count++
+	}
+	if count > 0 {
+		tmm.logger.Debug("Tile colliders built:", layer.Name, count)
+	}
+}
+
+func (tmm *TilemapsManager) buildProjectedLayerColliders(tm *TileMap, layer *Layer, bounds image.Rectangle, scale float64) {
+	outline := Object{Polygon: tm.Map.tileOutline()}
+	count := 0
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			gid, flags := DecodeGID(layer.gidAt(x, y))
+			tile, ok := tm.Tiles[gid]
+			if gid == 0 || !ok {
+				continue
+			}
+			cell := image.Rect(x, y, x+1, y+1)
+			px, py := tm.Map.tileToPixel(x, y)
+			px, py = px+tm.WorldX, py+tm.WorldY
+			solid, tileOneWay := tileFlag(tile.Properties, "solid"), tileFlag(tile.Properties, "oneway")
+			if solid || tileOneWay {
+				tm.addLayerCollider(layer, tmm.addShapeCollider(outline, px, py, scale, colliderGroup(Object{}, layer.Props(), "tile"), !solid), cell)
+				count++
+			}
+			img, ok := tm.CachedTiles[gid]
+			if !ok {
+				continue
+			}
+			iw, ih := float64(img.Bounds().Dx()), float64(img.Bounds().Dy())
+			offset := tm.tileOffsets[gid]
+			originX, originY := px+float64(offset.X), py+float64(tm.Map.TileHeight)-ih+float64(offset.Y)
+			for _, obj := range tile.Collision {
+				if obj.Point {
+					continue
+				}
+				oneWay := tileOneWay || obj.Type == "oneway" || obj.GetProperty("oneway") == true
+				tm.addLayerCollider(layer, tmm.addShapeCollider(tm.flipTileObject(obj, iw, ih, flags), originX, originY, scale, colliderGroup(obj, layer.Props(), "tile"), oneWay), cell)
+				count++
+			}
+		}
+	}
+	if count > 0 {
+		tmm.logger.Debug("Tile colliders built:", layer.Name, count)
+	}
+}
+
+func (tmm *TilemapsManager) addShapeCollider(obj Object, originX, originY, scale float64, group string, oneWay bool) Entity {
+	scaled := obj.scaled(scale)
+	scaled.X, scaled.Y = originX*scale+scaled.X, originY*scale+scaled.Y
+	entity := scaled.AddCollider(group)
+	if oneWay {
+		entity.AddComponent("oneway", true)
+	}
+	return entity
+}
+
+func colliderGroup(obj Object, props Properties, fallback string) string {
+	if group := obj.Props().GetString("group"); group != "" {
+		return group
+	}
+	if group := props.GetString("group"); group != "" {
+		return group
+	}
+	return fallback
+}
+
+func mergeTileRects(cells []tileSolid, bounds image.Rectangle, kind tileSolid, rowsOnly bool) []image.Rectangle {
+	w, h := bounds.Dx(), bounds.Dy()
+	used := make([]bool, len(cells))
+	free := func(x, y int) bool {
+		return cells[y*w+x] == kind && !used[y*w+x]
+	}
+	var rects []image.Rectangle
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if !free(x, y) {
+				continue
+			}
+			rw := 1
+			for x+rw < w && free(x+rw, y) {
+				rw++
+			}
+			rh := 1
+			for !rowsOnly && y+rh < h {
+				full := true
+				for i := 0; i < rw; i++ {
+					if !free(x+i, y+rh) {
+						full = false
+						break
+					}
+				}
+				if !full {
+					break
+				}
+				rh++
+			}
+			for j := 0; j < rh; j++ {
+				for i := 0; i < rw; i++ {
+					used[(y+j)*w+x+i] = true
+				}
+			}
+			rects = append(rects, image.Rect(x, y, x+rw, y+rh).Add(bounds.Min))
+		}
+	}
+	return rects
+}
+
+func tileFlag(props map[string]interface{}, name string) bool {
+	v, _ := props[name].(bool)
+	return v
+}
+
+func isRectObject(obj Object) bool {
+	return !obj.Ellipse && !obj.Point && len(obj.Polygon) == 0 && obj.Rotation == 0
+}
+
+func (tm *TileMap) flipTileObject(obj Object, tw, th float64, flags uint32) Object {
+	if flags == 0 || tm.Map.Orientation == "hexagonal" {
+		return obj
+	}
+	points := make([]Vector, len(obj.Polygon))
+	copy(points, obj.Polygon)
+	obj.Polygon = points
+	if flags&FlipDiagonal != 0 {
+		obj.X, obj.Y = obj.Y, obj.X
+		obj.Width, obj.Height = obj.Height, obj.Width
+		obj.Rotation = -obj.Rotation
+		for i, pt := range obj.Polygon {
+			obj.Polygon[i] = Vector{pt.Y, pt.X}
+		}
+		tw, th = th, tw
+	}
+	if flags&FlipHorizontal != 0 {
+		obj.X = tw - obj.X - obj.Width
+		obj.Rotation = -obj.Rotation
+		for i, pt := range obj.Polygon {
+			obj.Polygon[i].X = -pt.X
+		}
+	}
+	if flags&FlipVertical != 0 {
+		obj.Y = th - obj.Y - obj.Height
+		obj.Rotation = -obj.Rotation
+		for i, pt := range obj.Polygon {
+			obj.Polygon[i].Y = -pt.Y
+		}
+	}
+	return obj
+}
@@ -0,0 +1,163 @@
+package gobonsai
+
+import (
+	"fmt"
+	"image"
+)
+
+func (tm *TileMap) findLayer(name string) *Layer {
+	var find func(layers []Layer) *Layer
+	find = func(layers []Layer) *Layer {
+		for i := range layers {
+			if layers[i].Name == name {
+				return &layers[i]
+			}
+			if l := find(layers[i].Layers); l != nil {
+				return l
+			}
+		}
+		return nil
+	}
+	return find(tm.Map.Layers)
+}
+
+func (l *Layer) setGID(x, y, raw int) bool {
+	if len(l.Chunks) == 0 {
+		if x < 0 || y < 0 || x >= l.Width || y >= l.Height {
+			return false
+		}
+		if len(l.Data) < l.Width*l.Height {
+			data := make([]int, l.Width*l.Height)
+			copy(data, l.Data)
+			l.Data = data
+		}
+		l.Data[y*l.Width+x] = raw
+		return true
+	}
+	c := l.chunkAt(x, y)
+	if c == nil {
+		if raw == 0 {
+			return true
+		}
+		cw, ch := l.Chunks[0].Width, l.Chunks[0].Height
+		if cw <= 0 || ch <= 0 {
+			return false
+		}
+		l.Chunks = append(l.Chunks, Chunk{
+			X:      floorDiv(x, cw) * cw,
+			Y:      floorDiv(y, ch) * ch,
+			Width:  cw,
+			Height: ch,
+			Data:   make([]int, cw*ch),
+		})
+		l.chunkIndex = nil
+		c = &l.Chunks[len(l.Chunks)-1]
+	}
+	c.Data[(y-c.Y)*c.Width+(x-c.X)] = raw
+	return true
+}
+
+func (tm *TileMap) hasCollision(gid int) bool {
+	tile, ok := tm.Tiles[gid]
+	return ok && (tileFlag(tile.Properties, "solid") || tileFlag(tile.Properties, "oneway") || len(tile.Collision) > 0)
+}
+
+func (tmm *TilemapsManager) currentMap() (*TileMap, bool) {
+	tm, ok := tmm.tileMaps[tmm.currentTileMap]
+	return tm, ok
+}
+
+func (tmm *TilemapsManager) GetTileAt(layer string, x, y int) int {
+	tmm.mu.Lock()
+	defer tmm.mu.Unlock()
+	tm, ok := tmm.currentMap()
+	if !ok {
+		return 0
+	}
+	if l := tm.findLayer(layer); l != nil {
+		return l.gidAt(x, y)
+	}
+	return 0
+}
+
+func (tmm *TilemapsManager) GetTile(gid int) *Tile {
+	tmm.mu.Lock()
+	defer tmm.mu.Unlock()
+	tm, ok := tmm.currentMap()
+	if !ok {
+		return nil
+	}
+	gid, _ = DecodeGID(gid)
+	return tm.Tiles[gid]
+}
+
+func (tmm *TilemapsManager) GetTileProperty(layer string, x, y int, name string) interface{} {
+	if tile := tmm.GetTile(tmm.GetTileAt(layer, x, y)); tile != nil {
+		return tile.Properties[name]
+	}
+	return nil
+}
+
+func (tmm *TilemapsManager) WorldToTile(x, y float64) (int, int) {
+	tmm.mu.Lock()
+	defer tmm.mu.Unlock()
+	tm, ok := tmm.currentMap()
+	if !ok || tmm.scale == 0 {
+		return 0, 0
+	}
+	return tm.Map.pixelToTile(x/tmm.scale-tm.WorldX, y/tmm.scale-tm.WorldY)
+}
+
+func (tmm *TilemapsManager) TileToWorld(x, y int) (float64, float64) {
+	tmm.mu.Lock()
+	defer tmm.mu.Unlock()
+	tm, ok := tmm.currentMap()
+	if !ok {
+		return 0, 0
+	}
+	px, py := tm.Map.tileToPixel(x, y)
+	return (tm.WorldX + px) * tmm.scale, (tm.WorldY + py) * tmm.scale
+}
+
+func (tmm *TilemapsManager) SetTileAt(layer string, x, y, gid int) error {
+	tmm.mu.Lock()
+	defer tmm.mu.Unlock()
+	tm, ok := tmm.currentMap()
+	if !ok {
+		return fmt.Errorf("no tilemap set")
+	}
+	l := tm.findLayer(layer)
+	if l == nil || l.Type != "tilelayer" {
+		return fmt.Errorf("tile layer %s not found", layer)
+	}
+	return tmm.setTiles(tm, l, []tileEdit{{x: x, y: y, gid: gid}})
+}
+
+type tileEdit struct {
+	x, y, gid int
+}
+
+func (tmm *TilemapsManager) setTiles(tm *TileMap, l *Layer, edits []tileEdit) error {
+	var dirty image.Rectangle
+	for _, e := range edits {
+		old, _ := DecodeGID(l.gidAt(e.x, e.y))
+		if !l.setGID(e.x, e.y, e.gid) {
+			return fmt.Errorf("tile %d,%d outside layer %s", e.x, e.y, l.Name)
+		}
+		tm.invalidateTile(l, e.x, e.y)
+		for _, g := range tm.navGrids {
+			g.Refresh(e.x, e.y)
+		}
+		if next, _ := DecodeGID(e.gid); tm.hasCollision(old) || tm.hasCollision(next) {
+			dirty = dirty.Union(image.Rect(e.x, e.y, e.x+1, e.y+1))
+		}
+	}
+	if !dirty.Empty() {
+		last := Mecs.LastEntity()
+		tmm.rebuildLayerColliders(tm, l, dirty)
+		if tmm.streaming {
+			tmm.tagRoomEntities(tm.name, last)
+		}
+	}
+	return nil
+}
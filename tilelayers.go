@@ -0,0 +1,109 @@
+package gobonsai
+
+type LayerContext struct {
+	TileMap    *TileMap
+	Layer      *Layer
+	Properties Properties
+	Scale      float64
+}
+
+type LayerHandler func(ctx LayerContext)
+
+type layerHandlers struct {
+	byName     map[string]LayerHandler
+	byClass    map[string]LayerHandler
+	byProperty map[string]LayerHandler
+}
+
+func (l *Layer) GetProperty(name string) interface{} {
+	return l.Props().Get(name)
+}
+
+func (l *Layer) Props() Properties {
+	return NewProperties(l.RawProps)
+}
+
+func (tmm *TilemapsManager) AddLayerHandler(name string, handler LayerHandler) {
+	tmm.mu.Lock()
+	defer tmm.mu.Unlock()
+	tmm.layerHandlers.byName[name] = handler
+}
+
+func (tmm *TilemapsManager) AddLayerClassHandler(class string, handler LayerHandler) {
+	tmm.mu.Lock()
+	defer tmm.mu.Unlock()
+	tmm.layerHandlers.byClass[class] = handler
+}
+
+func (tmm *TilemapsManager) AddLayerPropertyHandler(property string, handler LayerHandler) {
+	tmm.mu.Lock()
+	defer tmm.mu.Unlock()
+	tmm.layerHandlers.byProperty[property] = handler
+}
+
+func (tmm *TilemapsManager) RemoveLayerHandler(name string) {
+	tmm.mu.Lock()
+	defer tmm.mu.Unlock()
+	delete(tmm.layerHandlers.byName, name)
+	delete(tmm.layerHandlers.byClass, name)
+	delete(tmm.layerHandlers.byProperty, name)
+}
+
+func (tmm *TilemapsManager) registerDefaultLayerHandlers() {
+	tmm.layerHandlers.byName["entities"] = tmm.handleEntities
+	tmm.layerHandlers.byClass["entities"] = tmm.handleEntities
+	tmm.layerHandlers.byName["collider"] = tmm.handleColliders
+	tmm.layerHandlers.byClass["collider"] = tmm.handleColliders
+}
+
+func (tmm *TilemapsManager) handleLayers(tm *TileMap, layers []Layer, inherited Properties, scale float64) {
+	for i := range layers {
+		layer := &layers[i]
+		props := inherited.Merge(layer.Props())
+		ctx := LayerContext{TileMap: tm, Layer: layer, Properties: props, Scale: scale}
+		if handler, ok := tmm.layerHandlers.byName[layer.Name]; ok {
+			handler(ctx)
+		} else if handler, ok := tmm.layerHandlers.byClass[layer.Class]; ok && layer.Class != "" {
+			handler(ctx)
+		}
+		for _, prop := range layer.RawProps {
+			if handler, ok := tmm.layerHandlers.byProperty[prop.Name]; ok && prop.Value != false {
+				handler(ctx)
+			}
+		}
+		tmm.handleLayers(tm, layer.Layers, props, scale)
+	}
+}
+
+func (tm *TileMap) worldObject(obj Object) Object {
+	obj = tm.Map.projectObject(obj)
+	obj.X += tm.WorldX
+	obj.Y += tm.WorldY
+	return obj
+}
+
+func (tmm *TilemapsManager) handleEntities(ctx LayerContext) {
+	for _, obj := range ctx.Layer.Objects {
+		scaled := ctx.TileMap.worldObject(obj).scaled(ctx.Scale)
+		ctx.TileMap.Objects[obj.ID] = scaled
+		if callback, ok := tmm.callbacks[scaled.Type]; ok {
+			callback(scaled)
+		} else if scaled.Type != "" {
+			tmm.logger.Debug("callback not found:", scaled.Type)
+		}
+	}
+}
+
+func (tmm *TilemapsManager) handleColliders(ctx LayerContext) {
+	for _, obj := range ctx.Layer.Objects {
+		obj = ctx.TileMap.worldObject(obj)
+		if obj.Point {
+			continue
+		}
+		if isRectObject(obj) {
+			Mphysics.AddCollider(obj.X*ctx.Scale, obj.Y*ctx.Scale, obj.Width*ctx.Scale, obj.Height*ctx.Scale)
+			continue
+		}
+		obj.scaled(ctx.Scale).AddCollider(colliderGroup(obj, ctx.Properties, "collider"))
+	}
+}
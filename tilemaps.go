@@ -5,25 +5,52 @@ import (
 	"encoding/json"
 	"fmt"
 	"image"
+	"image/color"
+	"math"
 	"os"
 	"path/filepath"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
 )
 
 type Layer struct {
-	Name    string   `json:"name"`
-	Type    string   `json:"type"`
-	Data    []int    `json:"data"`
-	Objects []Object `json:"objects"`
-	Width   int      `json:"width"`
-	Height  int      `json:"height"`
-	Visible bool     `json:"visible"`
-	Opacity float64  `json:"opacity"`
-	Layers  []Layer  `json:"layers"`
+	Name      string     `json:"name"`
+	Type      string     `json:"type"`
+	Class     string     `json:"class"`
+	Data      []int      `json:"data"`
+	Chunks    []Chunk    `json:"chunks"`
+	Objects   []Object   `json:"objects"`
+	StartX    int        `json:"startx"`
+	StartY    int        `json:"starty"`
+	Width     int        `json:"width"`
+	Height    int        `json:"height"`
+	Visible   bool       `json:"visible"`
+	Opacity   float64    `json:"opacity"`
+	OffsetX   float64    `json:"offsetx"`
+	OffsetY   float64    `json:"offsety"`
+	ParallaxX float64    `json:"parallaxx"`
+	ParallaxY float64    `json:"parallaxy"`
+	TintColor string     `json:"tintcolor,omitempty"`
+	Image     string     `json:"image,omitempty"`
+	RepeatX   bool       `json:"repeatx,omitempty"`
+	RepeatY   bool       `json:"repeaty,omitempty"`
+	RawProps  []Property `json:"properties"`
+	Layers    []Layer    `json:"layers"`
+
+	chunkIndex map[image.Point]int
+}
+
+type Chunk struct {
+	X      int   `json:"x"`
+	Y      int   `json:"y"`
+	Width  int   `json:"width"`
+	Height int   `json:"height"`
+	Data   []int `json:"data"`
 }
 
 type Object struct {
@@ -36,11 +63,19 @@ type Object struct {
 	Height   float64    `json:"height"`
 	RawProps []Property `json:"properties"`
 	GID      int        `json:"gid,omitempty"`
+	Rotation float64    `json:"rotation"`
+	Ellipse  bool       `json:"ellipse,omitempty"`
+	Point    bool       `json:"point,omitempty"`
+	Polygon  []Vector   `json:"polygon,omitempty"`
+	Template string     `json:"template,omitempty"`
+	Visible  bool       `json:"visible"`
 }
 
 type Property struct {
-	Name  string      `json:"name"`
-	Value interface{} `json:"value"`
+	Name         string      `json:"name"`
+	Type         string      `json:"type,omitempty"`
+	PropertyType string      `json:"propertytype,omitempty"`
+	Value        interface{} `json:"value"`
 }
 
 type AnimationFrame struct {
@@ -49,9 +84,24 @@ type AnimationFrame struct {
 }
 
 type TilesetTile struct {
-	ID         int              `json:"id"`
-	Properties []Property       `json:"properties"`
-	Animation  []AnimationFrame `json:"animation"`
+	ID          int              `json:"id"`
+	Type        string           `json:"type,omitempty"`
+	Properties  []Property       `json:"properties"`
+	Animation   []AnimationFrame `json:"animation"`
+	ObjectGroup *Layer           `json:"objectgroup,omitempty"`
+	Terrain     []int            `json:"terrain,omitempty"`
+	Image       string           `json:"image,omitempty"`
+	ImageWidth  int              `json:"imagewidth,omitempty"`
+	ImageHeight int              `json:"imageheight,omitempty"`
+	X           int              `json:"x,omitempty"`
+	Y           int              `json:"y,omitempty"`
+	Width       int              `json:"width,omitempty"`
+	Height      int              `json:"height,omitempty"`
+}
+
+type TileOffset struct {
+	X int `json:"x"`
+	Y int `json:"y"`
 }
 
 type AnimationInfo struct {
@@ -65,6 +115,7 @@ type Tile struct {
 	ID            int
 	Properties    map[string]interface{}
 	AnimationInfo *AnimationInfo
+	Collision     []Object
 }
 
 type Tileset struct {
@@ -75,7 +126,15 @@ type Tileset struct {
 	TileHeight  int           `json:"tileheight"`
 	ImageWidth  int           `json:"imagewidth"`
 	ImageHeight int           `json:"imageheight"`
+	Margin      int           `json:"margin"`
+	Spacing     int           `json:"spacing"`
+	Columns     int           `json:"columns"`
+	TileCount   int           `json:"tilecount"`
+	TileOffset  TileOffset    `json:"tileoffset"`
 	Tiles       []TilesetTile `json:"tiles"`
+	RawProps    []Property    `json:"properties"`
+	WangSets    []WangSet     `json:"wangsets,omitempty"`
+	Terrains    []Terrain     `json:"terrains,omitempty"`
 }
 
 type EmbeddedTileset struct {
@@ -87,16 +146,34 @@ type EmbeddedTileset struct {
 	TileHeight  int           `json:"tileheight"`
 	ImageWidth  int           `json:"imagewidth"`
 	ImageHeight int           `json:"imageheight"`
+	Margin      int           `json:"margin"`
+	Spacing     int           `json:"spacing"`
+	Columns     int           `json:"columns"`
+	TileCount   int           `json:"tilecount"`
+	TileOffset  TileOffset    `json:"tileoffset"`
 	Tiles       []TilesetTile `json:"tiles"`
+	RawProps    []Property    `json:"properties"`
+	WangSets    []WangSet     `json:"wangsets,omitempty"`
+	Terrains    []Terrain     `json:"terrains,omitempty"`
 }
 
 type Map struct {
-	Width      int               `json:"width"`
-	Height     int               `json:"height"`
-	TileWidth  int               `json:"tilewidth"`
-	TileHeight int               `json:"tileheight"`
-	Layers     []Layer           `json:"layers"`
-	Tilesets   []EmbeddedTileset `json:"tilesets"`
+	Width       int               `json:"width"`
+	Height      int               `json:"height"`
+	TileWidth   int               `json:"tilewidth"`
+	TileHeight  int               `json:"tileheight"`
+	Orientation string            `json:"orientation"`
+	RenderOrder string            `json:"renderorder"`
+	Infinite    bool              `json:"infinite"`
+	Layers      []Layer           `json:"layers"`
+	Tilesets    []EmbeddedTileset `json:"tilesets"`
+	RawProps    []Property        `json:"properties"`
+
+	ParallaxOriginX float64 `json:"parallaxoriginx"`
+	ParallaxOriginY float64 `json:"parallaxoriginy"`
+	StaggerAxis     string  `json:"staggeraxis,omitempty"`
+	StaggerIndex    string  `json:"staggerindex,omitempty"`
+	HexSideLength   int     `json:"hexsidelength,omitempty"`
 }
 
 type TileMap struct {
@@ -106,6 +183,135 @@ type TileMap struct {
 	Tiles         map[int]*Tile
 	CachedTiles   map[int]*ebiten.Image
 	Objects       map[int]Object
+	WorldX        float64
+	WorldY        float64
+	name          string
+	path          string
+	sources       []string
+	modTimes      map[string]time.Time
+	tileSheets    map[int]*ebiten.Image
+	tileOffsets   map[int]image.Point
+	overhang      image.Point
+	layerImages   map[string]*ebiten.Image
+	renderCache   map[*Layer]map[image.Point]*renderChunk
+	colliders     map[*Layer][]tileCollider
+	navGrids      []*NavGrid
+	wangSets      map[string]*wangIndex
+	tileAnims     map[tileInstance]*TileAnimation
+	animClock     float64
+}
+
+const (
+	FlipHorizontal uint32 = 0x80000000
+	FlipVertical   uint32 = 0x40000000
+	FlipDiagonal   uint32 = 0x20000000
+	RotateHex120   uint32 = 0x10000000
+	gidFlagsMask          = FlipHorizontal | FlipVertical | FlipDiagonal | RotateHex120
+)
+
+func DecodeGID(raw int) (int, uint32) {
+	v := uint32(raw)
+	return int(v &^ gidFlagsMask), v & gidFlagsMask
+}
+
+func EncodeGID(gid int, flags uint32) int {
+	return int(uint32(gid) | flags&gidFlagsMask)
+}
+
+func (tm *TileMap) resolveGID(gid int) int {
+	if tile, exists := tm.Tiles[gid]; exists && tile.AnimationInfo != nil {
+		currentFrame := tile.AnimationInfo.Frames[tile.AnimationInfo.CurrentFrame]
+		gid = tile.AnimationInfo.BaseGID + currentFrame.TileID
+	}
+	return gid
+}
+
+func (tm *TileMap) tileImage(gid int) (*ebiten.Image, bool) {
+	img, ok := tm.CachedTiles[tm.resolveGID(gid)]
+	return img, ok
+}
+
+func (l *Layer) tileBounds() image.Rectangle {
+	if len(l.Chunks) == 0 {
+		return image.Rect(0, 0, l.Width, l.Height)
+	}
+	var bounds image.Rectangle
+	for _, c := range l.Chunks {
+		bounds = bounds.Union(image.Rect(c.X, c.Y, c.X+c.Width, c.Y+c.Height))
+	}
+	return bounds
+}
+
+func (l *Layer) gidAt(x, y int) int {
+	if len(l.Chunks) == 0 {
+		if x < 0 || y < 0 || x >= l.Width || y >= l.Height || y*l.Width+x >= len(l.Data) {
+			return 0
+		}
+		return l.Data[y*l.Width+x]
+	}
+	if c := l.chunkAt(x, y); c != nil {
+		if idx := (y-c.Y)*c.Width + (x - c.X); idx < len(c.Data) {
+			return c.Data[idx]
+		}
+	}
+	return 0
+}
+
+func (l *Layer) chunkAt(x, y int) *Chunk {
+	if len(l.Chunks) == 0 || l.Chunks[0].Width <= 0 || l.Chunks[0].Height <= 0 {
+		return nil
+	}
+	cw, ch := l.Chunks[0].Width, l.Chunks[0].Height
+	if l.chunkIndex == nil {
+		l.chunkIndex = make(map[image.Point]int, len(l.Chunks))
+		for i, c := range l.Chunks {
+			l.chunkIndex[image.Pt(floorDiv(c.X, cw), floorDiv(c.Y, ch))] = i
+		}
+	}
+	i, ok := l.chunkIndex[image.Pt(floorDiv(x, cw), floorDiv(y, ch))]
+	if !ok {
+		return nil
+	}
+	c := &l.Chunks[i]
+	if x < c.X || y < c.Y || x >= c.X+c.Width || y >= c.Y+c.Height {
+		return nil
+	}
+	return c
+}
+
+func (tm *TileMap) flipTile(geo *ebiten.GeoM, img *ebiten.Image, flags uint32) {
+	if flags == 0 {
+		return
+	}
+	w, h := float64(img.Bounds().Dx()), float64(img.Bounds().Dy())
+	geo.Translate(-w/2, -h/2)
+	if tm.Map.Orientation == "hexagonal" {
+		if flags&FlipDiagonal != 0 {
+			geo.Rotate(math.Pi / 3)
+		}
+		if flags&RotateHex120 != 0 {
+			geo.Rotate(2 * math.Pi / 3)
+		}
+	} else if flags&FlipDiagonal != 0 {
+		geo.Concat(diagonalFlip())
+		w, h = h, w
+	}
+	if flags&FlipHorizontal != 0 {
+		geo.Scale(-1, 1)
+	}
+	if flags&FlipVertical != 0 {
+		geo.Scale(1, -1)
+	}
+	geo.Translate(w/2, h/2)
+}
+
+func diagonalFlip() ebiten.GeoM {
+	var m ebiten.GeoM
+	m.SetElement(0, 0, 0)
+	m.SetElement(0, 1, 1)
+	m.SetElement(1, 0, 1)
+	m.SetElement(1, 1, 0)
+	return m
 }
 
 type TilemapsManager struct {
@@ -114,15 +320,34 @@ type TilemapsManager struct {
 	logger         *Logger
 	mu             sync.Mutex
 	callbacks      map[string]func(Object)
+	layerHandlers  layerHandlers
 	scale          float64
+	batch          tileBatch
+	world          *World
+	streaming      bool
+	transitions    []func(from, to string)
+	animSpeed      float64
+	animPaused     bool
+	visibleMaps    map[string]bool
+	showObjects    bool
+	reloadTicks    int
+	reloadErr      error
 }
 
 func NewTilemapsManager() *TilemapsManager {
-	return &TilemapsManager{
+	tmm := &TilemapsManager{
 		tileMaps:  make(map[string]*TileMap),
 		logger:    NewLogger("bonsai:tilemap"),
 		callbacks: make(map[string]func(Object)),
+		animSpeed: 1,
+		layerHandlers: layerHandlers{
+			byName:     make(map[string]LayerHandler),
+			byClass:    make(map[string]LayerHandler),
+			byProperty: make(map[string]LayerHandler),
+		},
 	}
+	tmm.registerDefaultLayerHandlers()
+	return tmm
 }
 
 func (tmm *TilemapsManager) AddCallback(name string, callback func(Object)) {
@@ -146,59 +371,116 @@ func (tmm *TilemapsManager) GetTilemaps() map[string]*TileMap {
 }
 
 func (obj Object) GetProperty(name string) interface{} {
-	for _, prop := range obj.RawProps {
-		if prop.Name == name {
-			return prop.Value
+	return obj.Props().Get(name)
+}
+
+func (obj Object) Props() Properties {
+	return NewProperties(obj.RawProps)
+}
+
+func (obj Object) scaled(scale float64) Object {
+	scaled := obj
+	scaled.X *= scale
+	scaled.Y *= scale
+	scaled.Width *= scale
+	scaled.Height *= scale
+	scaled.Polygon = nil
+	for _, pt := range obj.Polygon {
+		scaled.Polygon = append(scaled.Polygon, pt.Scale(scale))
+	}
+	return scaled
+}
+
+func (obj Object) ToCollider(group string) *ColliderComponent {
+	rotation := obj.Rotation * math.Pi / 180
+	center := Vector{obj.Width / 2, obj.Height / 2}.Rotate(rotation)
+	switch {
+	case len(obj.Polygon) > 0 && isConvex(obj.Polygon):
+		return &ColliderComponent{Group: group, Shape: ShapePolygon, Rotation: rotation, Points: obj.Polygon}
+	case len(obj.Polygon) > 0:
+		c := &ColliderComponent{Group: group}
+		for _, tri := range triangulate(obj.Polygon) {
+			c.Parts = append(c.Parts, ColliderPart{Shape: ShapePolygon, Rotation: rotation, Points: tri})
+		}
+		return c
+	case obj.Ellipse && obj.Width == obj.Height:
+		return &ColliderComponent{Group: group, Shape: ShapeCircle, OffsetX: center.X, OffsetY: center.Y, Radius: obj.Width / 2}
+	case obj.Ellipse:
+		points := make([]Vector, 16)
+		for i := range points {
+			sin, cos := math.Sincos(2 * math.Pi * float64(i) / float64(len(points)))
+			points[i] = Vector{obj.Width / 2 * (1 + cos), obj.Height / 2 * (1 + sin)}
 		}
+		return &ColliderComponent{Group: group, Shape: ShapePolygon, Rotation: rotation, Points: points}
+	case rotation != 0:
+		return &ColliderComponent{Group: group, Shape: ShapeBox, OffsetX: center.X - obj.Width/2, OffsetY: center.Y - obj.Height/2,
+			Width: obj.Width, Height: obj.Height, Rotation: rotation}
+	default:
+		return &ColliderComponent{Group: group, Width: obj.Width, Height: obj.Height}
 	}
-	return nil
+}
+
+func (obj Object) AddCollider(group string) Entity {
+	return Mphysics.AddShapeCollider(obj.X, obj.Y, obj.ToCollider(group))
 }
 
 func (tmm *TilemapsManager) AddTilemap(name, path string) error {
-	tmm.mu.Lock()
-	defer tmm.mu.Unlock()
-	jsonPath := tmm.normalizeJSONPath(path)
-	data, err := tmm.loadFile(jsonPath)
+	tileMap, err := tmm.loadTilemap(name, path)
 	if err != nil {
-		return fmt.Errorf("failed to load tilemap: %w", err)
-	}
-	var m Map
-	if err := json.Unmarshal(data, &m); err != nil {
-		return fmt.Errorf("failed to parse map json: %w", err)
+		return err
 	}
-	tileMap := &TileMap{
-		Map:           &m,
+	tmm.mu.Lock()
+	defer tmm.mu.Unlock()
+	tmm.tileMaps[name] = tileMap
+	return nil
+}
+
+func newTileMap(name string, m *Map) *TileMap {
+	return &TileMap{
+		Map:           m,
+		name:          name,
 		Tilesets:      []*Tileset{},
 		TilesetImages: make(map[int]*ebiten.Image),
 		Tiles:         make(map[int]*Tile),
 		CachedTiles:   make(map[int]*ebiten.Image),
 		Objects:       make(map[int]Object),
+		tileSheets:    make(map[int]*ebiten.Image),
+		tileOffsets:   make(map[int]image.Point),
+		layerImages:   make(map[string]*ebiten.Image),
 	}
+}
+
+func (tmm *TilemapsManager) loadTilemap(name, path string) (*TileMap, error) {
+	jsonPath := tmm.normalizeJSONPath(path)
+	data, err := tmm.loadFile(jsonPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load tilemap: %w", err)
+	}
+	m, err := tmm.parseMap(jsonPath, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse map: %w", err)
+	}
+	tileMap := newTileMap(name, &m)
+	tileMap.path = path
+	tileMap.sources = append(tileMap.sources, jsonPath)
 	baseDir := filepath.Dir(jsonPath)
 	for _, emb := range m.Tilesets {
 		if emb.Source != "" {
 			tilesetPath := tmm.normalizeJSONPath(filepath.Join(baseDir, emb.Source))
 			tsData, err := tmm.loadFile(tilesetPath)
 			if err != nil {
-				return fmt.Errorf("failed to load tileset json (%s): %w", emb.Source, err)
+				return nil, fmt.Errorf("failed to load tileset json (%s): %w", emb.Source, err)
 			}
-			var ts Tileset
-			if err := json.Unmarshal(tsData, &ts); err != nil {
-				return fmt.Errorf("failed to parse tileset json (%s): %w", emb.Source, err)
+			ts, err := tmm.parseTileset(tilesetPath, tsData)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse tileset (%s): %w", emb.Source, err)
 			}
 			ts.FirstGID = emb.FirstGID
 			tileMap.Tilesets = append(tileMap.Tilesets, &ts)
-			imagePath := tmm.normalizeAssetPath(ts.Image)
-			imgData, err := tmm.loadFile(imagePath)
-			if err != nil {
-				return fmt.Errorf("failed to load tileset image: %w", err)
+			tileMap.sources = append(tileMap.sources, tilesetPath)
+			if err := tmm.loadTilesetImages(tileMap, &ts); err != nil {
+				return nil, err
 			}
-			img, _, err := ebitenutil.NewImageFromReader(bytes.NewReader(imgData))
-			if err != nil {
-				return fmt.Errorf("failed to decode tileset image: %w", err)
-			}
-			tileMap.TilesetImages[ts.FirstGID] = img
-			tmm.cacheTiles(ts, img, tileMap)
 		} else {
 			ts := &Tileset{
 				FirstGID:    emb.FirstGID,
@@ -208,24 +490,51 @@ func (tmm *TilemapsManager) AddTilemap(name, path string) error {
 				TileHeight:  emb.TileHeight,
 				ImageWidth:  emb.ImageWidth,
 				ImageHeight: emb.ImageHeight,
+				Margin:      emb.Margin,
+				Spacing:     emb.Spacing,
+				Columns:     emb.Columns,
+				TileCount:   emb.TileCount,
+				TileOffset:  emb.TileOffset,
 				Tiles:       emb.Tiles,
+				RawProps:    emb.RawProps,
+				WangSets:    emb.WangSets,
+				Terrains:    emb.Terrains,
 			}
 			tileMap.Tilesets = append(tileMap.Tilesets, ts)
-			imagePath := tmm.normalizeAssetPath(ts.Image)
-			imgData, err := tmm.loadFile(imagePath)
-			if err != nil {
-				return fmt.Errorf("failed to load tileset image: %w", err)
+			if err := tmm.loadTilesetImages(tileMap, ts); err != nil {
+				return nil, err
 			}
-			img, _, err := ebitenutil.NewImageFromReader(bytes.NewReader(imgData))
-			if err != nil {
-				return fmt.Errorf("failed to decode tileset image: %w", err)
-			}
-			tileMap.TilesetImages[ts.FirstGID] = img
-			tmm.cacheTiles(*ts, img, tileMap)
 		}
 	}
-	tmm.tileMaps[name] = tileMap
-	return nil
+	tileMap.indexWangSets()
+	if err := tmm.loadLayerImages(tileMap, tileMap.Map.Layers); err != nil {
+		return nil, fmt.Errorf("failed to load image layers: %w", err)
+	}
+	if err := tmm.resolveObjects(tileMap, baseDir); err != nil {
+		return nil, fmt.Errorf("failed to resolve objects: %w", err)
+	}
+	if Debug {
+		tileMap.modTimes = statSources(tileMap.sources)
+	}
+	return tileMap, nil
+}
+
+func (tmm *TilemapsManager) parseMap(path string, data []byte) (Map, error) {
+	if strings.EqualFold(filepath.Ext(path), ".tmx") {
+		return parseTMX(data)
+	}
+	var m Map
+	err := json.Unmarshal(data, &m)
+	return m, err
+}
+
+func (tmm *TilemapsManager) parseTileset(path string, data []byte) (Tileset, error) {
+	if strings.EqualFold(filepath.Ext(path), ".tsx") {
+		return parseTSX(data)
+	}
+	var ts Tileset
+	err := json.Unmarshal(data, &ts)
+	return ts, err
 }
 
 func (tmm *TilemapsManager) loadFile(path string) ([]byte, error) {
@@ -268,29 +577,108 @@ func (tmm *TilemapsManager) normalizeAssetPath(path string) string {
 	return path
 }
 
+func (tmm *TilemapsManager) loadImage(path string) (*ebiten.Image, error) {
+	data, err := tmm.loadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	img, _, err := ebitenutil.NewImageFromReader(bytes.NewReader(data))
+	return img, err
+}
+
+func (tmm *TilemapsManager) loadTilesetImages(tileMap *TileMap, ts *Tileset) error {
+	var sheet *ebiten.Image
+	if ts.Image != "" {
+		imagePath := tmm.normalizeAssetPath(ts.Image)
+		tileMap.sources = append(tileMap.sources, imagePath)
+		img, err := tmm.loadImage(imagePath)
+		if err != nil {
+			return fmt.Errorf("failed to load tileset image: %w", err)
+		}
+		tileMap.TilesetImages[ts.FirstGID] = img
+		sheet = img
+	}
+	for _, tsTile := range ts.Tiles {
+		if tsTile.Image == "" {
+			continue
+		}
+		gid := ts.FirstGID + tsTile.ID
+		imagePath := tmm.normalizeAssetPath(tsTile.Image)
+		tileMap.sources = append(tileMap.sources, imagePath)
+		img, err := tmm.loadImage(imagePath)
+		if err != nil {
+			return fmt.Errorf("failed to load tile %d image: %w", tsTile.ID, err)
+		}
+		tileMap.TilesetImages[gid] = img
+	}
+	tmm.cacheTiles(*ts, sheet, tileMap)
+	return nil
+}
+
 func (tmm *TilemapsManager) cacheTiles(ts Tileset, img *ebiten.Image, tileMap *TileMap) {
-	totalTiles := (ts.ImageWidth / ts.TileWidth) * (ts.ImageHeight / ts.TileHeight)
-	tpr := ts.ImageWidth / ts.TileWidth
-	for gid := ts.FirstGID; gid < ts.FirstGID+totalTiles; gid++ {
-		tid := gid - ts.FirstGID
-		sx := (tid % tpr) * ts.TileWidth
-		sy := (tid / tpr) * ts.TileHeight
-		tileMap.CachedTiles[gid] = img.SubImage(image.Rect(sx, sy, sx+ts.TileWidth, sy+ts.TileHeight)).(*ebiten.Image)
+	offset := image.Pt(ts.TileOffset.X, ts.TileOffset.Y)
+	cache := func(gid int, sheet *ebiten.Image, rect image.Rectangle) {
+		tileMap.CachedTiles[gid] = sheet.SubImage(rect).(*ebiten.Image)
+		tileMap.tileSheets[gid] = sheet
+		if offset != (image.Point{}) {
+			tileMap.tileOffsets[gid] = offset
+		}
+		tileMap.overhang.X = max(tileMap.overhang.X, rect.Dx()-tileMap.Map.TileWidth+abs(offset.X))
+		tileMap.overhang.Y = max(tileMap.overhang.Y, rect.Dy()-tileMap.Map.TileHeight+abs(offset.Y))
+	}
+	tw, th := ts.TileWidth, ts.TileHeight
+	if img != nil && tw > 0 && th > 0 {
+		iw, ih := ts.ImageWidth, ts.ImageHeight
+		if iw == 0 || ih == 0 {
+			iw, ih = img.Bounds().Dx(), img.Bounds().Dy()
+		}
+		columns := ts.Columns
+		if columns == 0 {
+			columns = (iw - 2*ts.Margin + ts.Spacing) / (tw + ts.Spacing)
+		}
+		count := ts.TileCount
+		if count == 0 && columns > 0 {
+			count = columns * ((ih - 2*ts.Margin + ts.Spacing) / (th + ts.Spacing))
+		}
+		for tid := 0; tid < count && columns > 0; tid++ {
+			sx := ts.Margin + (tid%columns)*(tw+ts.Spacing)
+			sy := ts.Margin + (tid/columns)*(th+ts.Spacing)
+			cache(ts.FirstGID+tid, img, image.Rect(sx, sy, sx+tw, sy+th))
+		}
 	}
 	for _, tsTile := range ts.Tiles {
+		gid := ts.FirstGID + tsTile.ID
+		src, ok := tileMap.TilesetImages[gid]
+		if tsTile.Image == "" || !ok {
+			continue
+		}
+		rect := src.Bounds()
+		if tsTile.Width > 0 && tsTile.Height > 0 {
+			rect = image.Rect(tsTile.X, tsTile.Y, tsTile.X+tsTile.Width, tsTile.Y+tsTile.Height).Intersect(rect)
+		}
+		cache(gid, src, rect)
+	}
+	for _, tsTile := range ts.Tiles {
+		gid := ts.FirstGID + tsTile.ID
+		tile := &Tile{
+			ID:         gid,
+			Properties: make(map[string]interface{}),
+		}
+		for name, prop := range tsTile.Props() {
+			tile.Properties[name] = prop.Value
+		}
+		if tsTile.ObjectGroup != nil {
+			tile.Collision = tsTile.ObjectGroup.Objects
+		}
 		if len(tsTile.Animation) > 0 {
-			gid := ts.FirstGID + tsTile.ID
-			tileMap.Tiles[gid] = &Tile{
-				ID:         gid,
-				Properties: make(map[string]interface{}),
-				AnimationInfo: &AnimationInfo{
-					Frames:       tsTile.Animation,
-					CurrentFrame: 0,
-					ElapsedTime:  0,
-					BaseGID:      ts.FirstGID,
-				},
+			tile.AnimationInfo = &AnimationInfo{
+				Frames:       tsTile.Animation,
+				CurrentFrame: 0,
+				ElapsedTime:  0,
+				BaseGID:      ts.FirstGID,
 			}
 		}
+		tileMap.Tiles[gid] = tile
 	}
 }
 
@@ -303,41 +691,17 @@ func (tmm *TilemapsManager) SetTilemap(name string, scale float64) error {
 	}
 	tmm.currentTileMap = name
 	tmm.scale = scale
-	tm := tmm.tileMaps[name]
-	tm.Objects = make(map[int]Object)
-	for _, tsLayer := range tm.Map.Layers {
-		if tsLayer.Name == "entities" {
-			for _, obj := range tsLayer.Objects {
-				scaledObj := Object{
-					ID:       obj.ID,
-					Name:     obj.Name,
-					Type:     obj.Type,
-					X:        obj.X * scale,
-					Y:        obj.Y * scale,
-					Width:    obj.Width * scale,
-					Height:   obj.Height * scale,
-					RawProps: obj.RawProps,
-					GID:      obj.GID,
-				}
-				tm.Objects[obj.ID] = scaledObj
-			}
-			for _, obj := range tm.Objects {
-				if callback, ok := tmm.callbacks[obj.Type]; ok {
-					callback(obj)
-				} else if obj.Type != "" {
-					tmm.logger.Error("callback not found: ", obj.Type)
-				}
-			}
-		}
-		if tsLayer.Name == "collider" {
-			for _, obj := range tsLayer.Objects {
-				Mphysics.AddCollider(obj.X*scale, obj.Y*scale, obj.Width*scale, obj.Height*scale)
-			}
-		}
-	}
+	tmm.streaming = false
+	tmm.spawnTilemap(tmm.tileMaps[name], scale)
 	return nil
 }
 
+func (tmm *TilemapsManager) spawnTilemap(tm *TileMap, scale float64) {
+	tm.Objects = make(map[int]Object)
+	tmm.handleLayers(tm, tm.Map.Layers, tm.Map.Props(), scale)
+	tmm.buildTileColliders(tm, scale)
+}
+
 func (tmm *TilemapsManager) GetObject(id float64) Object {
 	tmm.mu.Lock()
 	defer tmm.mu.Unlock()
@@ -347,6 +711,36 @@ func (tmm *TilemapsManager) GetObject(id float64) Object {
 	return Object{}
 }
 
+func (tmm *TilemapsManager) GetBounds() (float64, float64, float64, float64) {
+	tmm.mu.Lock()
+	defer tmm.mu.Unlock()
+	tm, ok := tmm.tileMaps[tmm.currentTileMap]
+	if !ok {
+		return 0, 0, 0, 0
+	}
+	x, y, w, h := tm.bounds()
+	return x * tmm.scale, y * tmm.scale, w * tmm.scale, h * tmm.scale
+}
+
+func (tm *TileMap) bounds() (float64, float64, float64, float64) {
+	bounds := image.Rect(0, 0, tm.Map.Width, tm.Map.Height)
+	if tm.Map.Infinite {
+		bounds = image.Rectangle{}
+		var collect func(layers []Layer)
+		collect = func(layers []Layer) {
+			for i := range layers {
+				if layers[i].Type == "tilelayer" {
+					bounds = bounds.Union(layers[i].tileBounds())
+				}
+				collect(layers[i].Layers)
+			}
+		}
+		collect(tm.Map.Layers)
+	}
+	x, y, w, h := tm.Map.pixelBounds(bounds)
+	return tm.WorldX + x, tm.WorldY + y, w, h
+}
+
 func (tmm *TilemapsManager) GetTilemap() string {
 	return tmm.currentTileMap
 }
@@ -354,99 +748,156 @@ func (tmm *TilemapsManager) GetTilemap() string {
 func (tmm *TilemapsManager) UpdateTilemap(delta float64) {
 	tmm.mu.Lock()
 	defer tmm.mu.Unlock()
-	if tmm.currentTileMap == "" {
+	if tmm.animPaused {
 		return
 	}
-	tm := tmm.tileMaps[tmm.currentTileMap]
-	for _, tile := range tm.Tiles {
-		if tile.AnimationInfo == nil {
-			continue
-		}
-		tile.AnimationInfo.ElapsedTime += delta * 1000
-		frame := tile.AnimationInfo.Frames[tile.AnimationInfo.CurrentFrame]
-		if tile.AnimationInfo.ElapsedTime >= float64(frame.Duration) {
-			tile.AnimationInfo.ElapsedTime -= float64(frame.Duration)
-			tile.AnimationInfo.CurrentFrame = (tile.AnimationInfo.CurrentFrame + 1) % len(tile.AnimationInfo.Frames)
-		}
+	for _, tm := range tmm.activeMaps() {
+		tm.updateAnimations(delta * 1000 * tmm.animSpeed)
 	}
 }
 
 func (tmm *TilemapsManager) DrawTilemap(screen *ebiten.Image, offsetX, offsetY float64, filterOut bool, layers ...string) {
+	var view ebiten.GeoM
+	view.Translate(offsetX, offsetY)
+	tmm.drawTilemap(screen, view, filterOut, layers)
+}
+
+func (tmm *TilemapsManager) DrawTilemapCamera(screen *ebiten.Image, camera *Camera, filterOut bool, layers ...string) {
+	tmm.drawTilemap(camera.Image(screen), camera.Matrix(), filterOut, layers)
+}
+
+func (tmm *TilemapsManager) drawTilemap(screen *ebiten.Image, view ebiten.GeoM, filterOut bool, layers []string) {
 	tmm.mu.Lock()
 	defer tmm.mu.Unlock()
 	if tmm.currentTileMap == "" {
 		return
 	}
-	tm := tmm.tileMaps[tmm.currentTileMap]
 	scale := tmm.scale
 	layerMap := make(map[string]bool)
 	for _, layer := range layers {
 		layerMap[layer] = true
 	}
-	for _, layer := range tm.Map.Layers {
-		if len(layers) == 0 || (filterOut && !layerMap[layer.Name]) || (!filterOut && layerMap[layer.Name]) {
-			tmm.drawLayerRecursive(screen, tm, &layer, offsetX, offsetY, scale, filterOut, layerMap)
+	vx, vy, vw, vh := visibleRect(view, screen.Bounds())
+	for _, tm := range tmm.activeMaps() {
+		x, y, w, h := tm.bounds()
+		if x*scale > vx+vw || y*scale > vy+vh || (x+w)*scale < vx || (y+h)*scale < vy {
+			continue
+		}
+		var mapView ebiten.GeoM
+		mapView.Translate(tm.WorldX*scale, tm.WorldY*scale)
+		mapView.Concat(view)
+		for i := range tm.Map.Layers {
+			layer := &tm.Map.Layers[i]
+			if len(layers) == 0 || (filterOut && !layerMap[layer.Name]) || (!filterOut && layerMap[layer.Name]) {
+				tmm.drawLayerRecursive(screen, tm, layer, mapView, rootLayerStyle(), scale, filterOut, layerMap)
+			}
 		}
 	}
 }
 
-func (tmm *TilemapsManager) drawLayerRecursive(screen *ebiten.Image, tm *TileMap, layer *Layer, offsetX, offsetY, scale float64, filterOut bool, layerMap map[string]bool) {
-	if layer.Visible && (len(layerMap) == 0 || (filterOut && !layerMap[layer.Name]) || (!filterOut && layerMap[layer.Name])) {
-		if layer.Type == "tilelayer" {
-			tmm.drawLayer(screen, tm, layer, offsetX, offsetY, scale)
-		} else if layer.Type == "objectgroup" {
-			tmm.drawObjects(screen, tm, layer, offsetX, offsetY, scale)
+func (tmm *TilemapsManager) drawLayerRecursive(screen *ebiten.Image, tm *TileMap, layer *Layer, view ebiten.GeoM, parent layerStyle, scale float64, filterOut bool, layerMap map[string]bool) {
+	if !layer.Visible {
+		return
+	}
+	style := parent.child(layer)
+	if len(layerMap) == 0 || (filterOut && !layerMap[layer.Name]) || (!filterOut && layerMap[layer.Name]) {
+		layerView := style.view(view, screen.Bounds(), tm.Map, scale)
+		switch layer.Type {
+		case "tilelayer":
+			tmm.drawLayer(screen, tm, layer, layerView, style, scale)
+		case "objectgroup":
+			tmm.drawObjects(screen, tm, layer, layerView, style, scale)
+		case "imagelayer":
+			tmm.drawImageLayer(screen, tm, layer, layerView, style, scale)
 		}
 	}
-	for _, subLayer := range layer.Layers {
-		tmm.drawLayerRecursive(screen, tm, &subLayer, offsetX, offsetY, scale, filterOut, layerMap)
+	for i := range layer.Layers {
+		tmm.drawLayerRecursive(screen, tm, &layer.Layers[i], view, style, scale, filterOut, layerMap)
 	}
 }
 
-func (tmm *TilemapsManager) drawObjects(screen *ebiten.Image, tm *TileMap, layer *Layer, offsetX, offsetY, scale float64) {
+func (tmm *TilemapsManager) drawObjects(screen *ebiten.Image, tm *TileMap, layer *Layer, view ebiten.GeoM, style layerStyle, scale float64) {
 	for _, obj := range layer.Objects {
+		if !obj.Visible {
+			continue
+		}
 		if obj.GID > 0 {
-			currentGID := obj.GID
-			if tile, exists := tm.Tiles[obj.GID]; exists && tile.AnimationInfo != nil {
-				currentFrame := tile.AnimationInfo.Frames[tile.AnimationInfo.CurrentFrame]
-				currentGID = tile.AnimationInfo.BaseGID + currentFrame.TileID
-			}
-			if img, ok := tm.CachedTiles[currentGID]; ok {
-				op := &ebiten.DrawImageOptions{}
-				op.GeoM.Scale(scale, scale)
-				op.GeoM.Translate(offsetX+obj.X, offsetY+obj.Y-float64(tm.Map.TileHeight)*scale)
-				screen.DrawImage(img, op)
-			}
+			tmm.drawTileObject(screen, tm, obj, view, style, scale)
+		}
+		if tmm.showObjects {
+			drawObjectShape(screen, tm.Map.projectObject(obj), tm.Map.Orientation == "isometric", view, scale)
 		}
 	}
 }
 
-func (tmm *TilemapsManager) drawLayer(screen *ebiten.Image, tm *TileMap, layer *Layer, offsetX, offsetY, scale float64) {
-	tw, th := tm.Map.TileWidth, tm.Map.TileHeight
-	for y := 0; y < layer.Height; y++ {
-		for x := 0; x < layer.Width; x++ {
-			idx := y*layer.Width + x
-			if idx >= len(layer.Data) {
-				continue
-			}
-			originalGID := layer.Data[idx]
-			if originalGID == 0 {
-				continue
-			}
-			currentGID := originalGID
-			if tile, exists := tm.Tiles[originalGID]; exists && tile.AnimationInfo != nil {
-				currentFrame := tile.AnimationInfo.Frames[tile.AnimationInfo.CurrentFrame]
-				currentGID = tile.AnimationInfo.BaseGID + currentFrame.TileID
-			}
-			sub, ok := tm.CachedTiles[currentGID]
-			if !ok {
-				continue
-			}
-			op := &ebiten.DrawImageOptions{}
-			op.GeoM.Scale(scale, scale)
-			op.GeoM.Translate(offsetX+float64(x*tw)*scale, offsetY+float64(y*th)*scale)
-			op.ColorM.Scale(1, 1, 1, layer.Opacity)
-			screen.DrawImage(sub, op)
+func (tmm *TilemapsManager) drawTileObject(screen *ebiten.Image, tm *TileMap, obj Object, view ebiten.GeoM, style layerStyle, scale float64) {
+	gid, flags := DecodeGID(obj.GID)
+	img, ok := tm.tileImage(gid)
+	if !ok {
+		return
+	}
+	iw, ih := float64(img.Bounds().Dx()), float64(img.Bounds().Dy())
+	w, h := obj.Width, obj.Height
+	if w == 0 || h == 0 {
+		w, h = iw, ih
+	}
+	op := &ebiten.DrawImageOptions{}
+	tm.flipTile(&op.GeoM, img, flags)
+	op.GeoM.Scale(w/iw, h/ih)
+	ax := 0.0
+	if tm.Map.Orientation == "isometric" {
+		ax = -w / 2
+	}
+	offset := tm.tileOffsets[tm.resolveGID(gid)]
+	op.GeoM.Translate(ax+float64(offset.X), float64(offset.Y)-h)
+	op.GeoM.Rotate(obj.Rotation * math.Pi / 180)
+	x, y := tm.Map.pixelToScreen(obj.X, obj.Y)
+	op.GeoM.Translate(x, y)
+	op.GeoM.Scale(scale, scale)
+	op.GeoM.Concat(view)
+	op.ColorScale = style.colorScale()
+	screen.DrawImage(img, op)
+}
+
+func (tmm *TilemapsManager) ShowObjects(show bool) {
+	tmm.mu.Lock()
+	defer tmm.mu.Unlock()
+	tmm.showObjects = show
+}
+
+func drawObjectShape(screen *ebiten.Image, obj Object, centered bool, view ebiten.GeoM, scale float64) {
+	col := color.RGBA{255, 255, 0, 255}
+	toScreen := func(pt Vector) (float32, float32) {
+		pt = pt.Rotate(obj.Rotation * math.Pi / 180)
+		x, y := view.Apply((obj.X+pt.X)*scale, (obj.Y+pt.Y)*scale)
+		return float32(x), float32(y)
+	}
+	var outline []Vector
+	switch {
+	case obj.Point:
+		x, y := toScreen(Vector{})
+		vector.StrokeCircle(screen, x, y, 3, 1, col, false)
+		return
+	case len(obj.Polygon) > 0:
+		outline = obj.Polygon
+	case obj.Ellipse:
+		outline = make([]Vector, 24)
+		for i := range outline {
+			sin, cos := math.Sincos(2 * math.Pi * float64(i) / float64(len(outline)))
+			outline[i] = Vector{obj.Width / 2 * (1 + cos), obj.Height / 2 * (1 + sin)}
 		}
+	case obj.GID > 0:
+		x0 := 0.0
+		if centered {
+			x0 = -obj.Width / 2
+		}
+		outline = []Vector{{x0, -obj.Height}, {x0 + obj.Width, -obj.Height}, {x0 + obj.Width, 0}, {x0, 0}}
+	default:
+		outline = []Vector{{0, 0}, {obj.Width, 0}, {obj.Width, obj.Height}, {0, obj.Height}}
+	}
+	for i, pt := range outline {
+		x0, y0 := toScreen(pt)
+		x1, y1 := toScreen(outline[(i+1)%len(outline)])
+		vector.StrokeLine(screen, x0, y0, x1, y1, 1, col, false)
 	}
 }
@@ -0,0 +1,324 @@
+package gobonsai
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+)
+
+const (
+	renderChunkSize  = 32
+	maxBatchVertices = 65532
+)
+
+type layerStyle struct {
+	offsetX   float64
+	offsetY   float64
+	parallaxX float64
+	parallaxY float64
+	r, g, b   float32
+	a         float32
+}
+
+func rootLayerStyle() layerStyle {
+	return layerStyle{parallaxX: 1, parallaxY: 1, r: 1, g: 1, b: 1, a: 1}
+}
+
+func (s layerStyle) child(l *Layer) layerStyle {
+	s.offsetX += l.OffsetX
+	s.offsetY += l.OffsetY
+	s.parallaxX *= l.ParallaxX
+	s.parallaxY *= l.ParallaxY
+	s.a *= float32(l.Opacity)
+	if l.TintColor != "" {
+		if c, err := parseTiledColor(l.TintColor); err == nil {
+			s.r *= float32(c.R) / 0xff
+			s.g *= float32(c.G) / 0xff
+			s.b *= float32(c.B) / 0xff
+			s.a *= float32(c.A) / 0xff
+		}
+	}
+	return s
+}
+
+func (s layerStyle) view(view ebiten.GeoM, screen image.Rectangle, m *Map, scale float64) ebiten.GeoM {
+	var geo ebiten.GeoM
+	geo.Translate(s.offsetX*scale, s.offsetY*scale)
+	if (s.parallaxX != 1 || s.parallaxY != 1) && view.IsInvertible() {
+		inv := view
+		inv.Invert()
+		cx, cy := inv.Apply(float64(screen.Min.X+screen.Max.X)/2, float64(screen.Min.Y+screen.Max.Y)/2)
+		geo.Translate((cx-m.ParallaxOriginX*scale)*(1-s.parallaxX), (cy-m.ParallaxOriginY*scale)*(1-s.parallaxY))
+	}
+	geo.Concat(view)
+	return geo
+}
+
+func (s layerStyle) colorScale() ebiten.ColorScale {
+	var cs ebiten.ColorScale
+	cs.Scale(s.r*s.a, s.g*s.a, s.b*s.a, s.a)
+	return cs
+}
+
+type renderChunk struct {
+	image   *ebiten.Image
+	dynamic bool
+	empty   bool
+}
+
+type tileBatch struct {
+	sheet    *ebiten.Image
+	vertices []ebiten.Vertex
+	indices  []uint16
+}
+
+func (b *tileBatch) add(screen, sheet *ebiten.Image, src image.Rectangle, geo ebiten.GeoM, style layerStyle) {
+	if b.sheet != sheet || len(b.vertices)+4 > maxBatchVertices {
+		b.flush(screen)
+		b.sheet = sheet
+	}
+	base := uint16(len(b.vertices))
+	w, h := float64(src.Dx()), float64(src.Dy())
+	for _, corner := range [4][2]float64{{0, 0}, {w, 0}, {0, h}, {w, h}} {
+		dx, dy := geo.Apply(corner[0], corner[1])
+		b.vertices = append(b.vertices, ebiten.Vertex{
+			DstX:   float32(dx),
+			DstY:   float32(dy),
+			SrcX:   float32(src.Min.X) + float32(corner[0]),
+			SrcY:   float32(src.Min.Y) + float32(corner[1]),
+			ColorR: style.r,
+			ColorG: style.g,
+			ColorB: style.b,
+			ColorA: style.a,
+		})
+	}
+	b.indices = append(b.indices, base, base+1, base+2, base+1, base+3, base+2)
+}
+
+func (b *tileBatch) flush(screen *ebiten.Image) {
+	if len(b.indices) > 0 && b.sheet != nil {
+		screen.DrawTriangles(b.vertices, b.indices, b.sheet, nil)
+	}
+	b.vertices = b.vertices[:0]
+	b.indices = b.indices[:0]
+	b.sheet = nil
+}
+
+func floorDiv(a, b int) int {
+	q := a / b
+	if a%b != 0 && (a < 0) != (b < 0) {
+		q--
+	}
+	return q
+}
+
+func (tm *TileMap) renderChunk(layer *Layer, cx, cy int) *renderChunk {
+	if tm.renderCache == nil {
+		tm.renderCache = make(map[*Layer]map[image.Point]*renderChunk)
+	}
+	cache, ok := tm.renderCache[layer]
+	if !ok {
+		cache = make(map[image.Point]*renderChunk)
+		tm.renderCache[layer] = cache
+	}
+	key := image.Pt(cx, cy)
+	if chunk, ok := cache[key]; ok {
+		return chunk
+	}
+
+	chunk := &renderChunk{empty: true}
+	rect := image.Rect(cx*renderChunkSize, cy*renderChunkSize, (cx+1)*renderChunkSize, (cy+1)*renderChunkSize).Intersect(layer.tileBounds())
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		for x := rect.Min.X; x < rect.Max.X; x++ {
+			raw := layer.gidAt(x, y)
+			if raw == 0 {
+				continue
+			}
+			chunk.empty = false
+			gid, _ := DecodeGID(raw)
+			if tile, exists := tm.Tiles[gid]; exists && tile.AnimationInfo != nil {
+				chunk.dynamic = true
+			}
+			if img, ok := tm.CachedTiles[gid]; ok && (img.Bounds().Dx() != tm.Map.TileWidth || img.Bounds().Dy() != tm.Map.TileHeight) {
+				chunk.dynamic = true
+			}
+			if _, ok := tm.tileOffsets[gid]; ok {
+				chunk.dynamic = true
+			}
+		}
+	}
+
+	if !chunk.empty && !chunk.dynamic {
+		tw, th := tm.Map.TileWidth, tm.Map.TileHeight
+		chunk.image = ebiten.NewImage(renderChunkSize*tw, renderChunkSize*th)
+		for y := rect.Min.Y; y < rect.Max.Y; y++ {
+			for x := rect.Min.X; x < rect.Max.X; x++ {
+				gid, flags := DecodeGID(layer.gidAt(x, y))
+				img, ok := tm.tileImage(gid)
+				if !ok {
+					continue
+				}
+				op := &ebiten.DrawImageOptions{}
+				tm.flipTile(&op.GeoM, img, flags)
+				op.GeoM.Translate(float64((x-cx*renderChunkSize)*tw), float64((y-cy*renderChunkSize)*th))
+				chunk.image.DrawImage(img, op)
+			}
+		}
+	}
+	cache[key] = chunk
+	return chunk
+}
+
+func (tm *TileMap) invalidateTile(layer *Layer, x, y int) {
+	cache, ok := tm.renderCache[layer]
+	if !ok {
+		return
+	}
+	key := image.Pt(floorDiv(x, renderChunkSize), floorDiv(y, renderChunkSize))
+	if chunk, ok := cache[key]; ok {
+		if chunk.image != nil {
+			chunk.image.Deallocate()
+		}
+		delete(cache, key)
+	}
+}
+
+func (tm *TileMap) invalidateRenderCache() {
+	for _, cache := range tm.renderCache {
+		for _, chunk := range cache {
+			if chunk.image != nil {
+				chunk.image.Deallocate()
+			}
+		}
+	}
+	tm.renderCache = nil
+}
+
+func (tm *TileMap) visibleLayerTiles(layer *Layer, view ebiten.GeoM, screen image.Rectangle, scale float64) image.Rectangle {
+	tw, th := tm.Map.TileWidth, tm.Map.TileHeight
+	vx, vy, vw, vh := visibleRect(view, screen)
+	visible := tm.Map.visibleTiles(vx/scale, vy/scale, vw/scale, vh/scale)
+	ox, oy := (tm.overhang.X+tw-1)/tw, (tm.overhang.Y+th-1)/th
+	return image.Rect(visible.Min.X-ox, visible.Min.Y-oy, visible.Max.X+ox, visible.Max.Y+oy).Intersect(layer.tileBounds())
+}
+
+func (tmm *TilemapsManager) drawLayer(screen *ebiten.Image, tm *TileMap, layer *Layer, view ebiten.GeoM, style layerStyle, scale float64) {
+	tw, th := tm.Map.TileWidth, tm.Map.TileHeight
+	if tw <= 0 || th <= 0 || scale <= 0 {
+		return
+	}
+	visible := tm.visibleLayerTiles(layer, view, screen.Bounds(), scale)
+	if visible.Empty() {
+		return
+	}
+	if !tm.Map.isOrthogonal() {
+		tm.Map.eachTile(visible, func(x, y int) {
+			tmm.drawTile(screen, tm, layer, x, y, view, style, scale)
+		})
+		tmm.batch.flush(screen)
+		return
+	}
+
+	for cy := floorDiv(visible.Min.Y, renderChunkSize); cy*renderChunkSize < visible.Max.Y; cy++ {
+		for cx := floorDiv(visible.Min.X, renderChunkSize); cx*renderChunkSize < visible.Max.X; cx++ {
+			chunk := tm.renderChunk(layer, cx, cy)
+			if chunk.empty {
+				continue
+			}
+			if chunk.image != nil {
+				tmm.batch.flush(screen)
+				op := &ebiten.DrawImageOptions{}
+				op.GeoM.Scale(scale, scale)
+				op.GeoM.Translate(float64(cx*renderChunkSize*tw)*scale, float64(cy*renderChunkSize*th)*scale)
+				op.GeoM.Concat(view)
+				op.ColorScale = style.colorScale()
+				screen.DrawImage(chunk.image, op)
+				continue
+			}
+			rect := image.Rect(cx*renderChunkSize, cy*renderChunkSize, (cx+1)*renderChunkSize, (cy+1)*renderChunkSize).Intersect(visible)
+			for y := rect.Min.Y; y < rect.Max.Y; y++ {
+				for x := rect.Min.X; x < rect.Max.X; x++ {
+					tmm.drawTile(screen, tm, layer, x, y, view, style, scale)
+				}
+			}
+		}
+	}
+	tmm.batch.flush(screen)
+}
+
+func (tmm *TilemapsManager) drawTile(screen *ebiten.Image, tm *TileMap, layer *Layer, x, y int, view ebiten.GeoM, style layerStyle, scale float64) {
+	raw := layer.gidAt(x, y)
+	if raw == 0 {
+		return
+	}
+	gid, flags := DecodeGID(raw)
+	frame := tm.instanceGID(layer, gid, x, y)
+	img, ok := tm.CachedTiles[frame]
+	if !ok {
+		return
+	}
+	px, py := tm.Map.tileToPixel(x, y)
+	var geo ebiten.GeoM
+	tm.flipTile(&geo, img, flags)
+	offset := tm.tileOffsets[frame]
+	geo.Translate(px+float64(offset.X), py+float64(tm.Map.TileHeight-img.Bounds().Dy()+offset.Y))
+	geo.Scale(scale, scale)
+	geo.Concat(view)
+	tmm.batch.add(screen, tm.tileSheets[frame], img.Bounds(), geo, style)
+}
+
+func (tmm *TilemapsManager) drawImageLayer(screen *ebiten.Image, tm *TileMap, layer *Layer, view ebiten.GeoM, style layerStyle, scale float64) {
+	img, ok := tm.layerImages[layer.Image]
+	if !ok {
+		return
+	}
+	w, h := float64(img.Bounds().Dx())*scale, float64(img.Bounds().Dy())*scale
+	if w <= 0 || h <= 0 {
+		return
+	}
+	vx, vy, vw, vh := visibleRect(view, screen.Bounds())
+	x0, x1, y0, y1 := 0.0, w, 0.0, h
+	if layer.RepeatX {
+		x0, x1 = math.Floor(vx/w)*w, vx+vw
+	}
+	if layer.RepeatY {
+		y0, y1 = math.Floor(vy/h)*h, vy+vh
+	}
+	for y := y0; y < y1; y += h {
+		for x := x0; x < x1; x += w {
+			op := &ebiten.DrawImageOptions{}
+			op.GeoM.Scale(scale, scale)
+			op.GeoM.Translate(x, y)
+			op.GeoM.Concat(view)
+			op.ColorScale = style.colorScale()
+			screen.DrawImage(img, op)
+		}
+	}
+}
+
+func (tmm *TilemapsManager) loadLayerImages(tm *TileMap, layers []Layer) error {
+	for i := range layers {
+		layer := &layers[i]
+		if layer.Type == "imagelayer" && layer.Image != "" {
+			if _, ok := tm.layerImages[layer.Image]; !ok {
+				data, err := tmm.loadFile(tmm.normalizeAssetPath(layer.Image))
+				if err != nil {
+					return fmt.Errorf("failed to load layer image (%s): %w", layer.Image, err)
+				}
+				img, _, err := ebitenutil.NewImageFromReader(bytes.NewReader(data))
+				if err != nil {
+					return fmt.Errorf("failed to decode layer image (%s): %w", layer.Image, err)
+				}
+				tm.layerImages[layer.Image] = img
+			}
+		}
+		if err := tmm.loadLayerImages(tm, layer.Layers); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package gobonsai
+
+import (
+	"image"
+	"testing"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+func newBenchTileMap(width, height int) (*TilemapsManager, *TileMap) {
+	tmm := NewTilemapsManager()
+	b := NewTileMapBuilder(width, height, 16, 16).AddTileLayer("ground")
+	tm := newTileMap("bench", &b.m)
+	layer := &tm.Map.Layers[0]
+	for i := range layer.Data {
+		layer.Data[i] = 1 + i%4
+	}
+	tmm.cacheTiles(Tileset{FirstGID: 1, TileWidth: 16, TileHeight: 16, TileCount: 4, Columns: 4}, ebiten.NewImage(64, 16), tm)
+	return tmm, tm
+}
+
+func buildTileVertices(tmm *TilemapsManager, tm *TileMap, layer *Layer, r image.Rectangle, view ebiten.GeoM, style layerStyle) int {
+	count := 0
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			tmm.drawTile(nil, tm, layer, x, y, view, style, 1)
+		}
+		count += len(tmm.batch.vertices) / 4
+		tmm.batch.vertices = tmm.batch.vertices[:0]
+		tmm.batch.indices = tmm.batch.indices[:0]
+	}
+	return count
+}
+
+func BenchmarkDrawLayer1000(b *testing.B) {
+	tmm, tm := newBenchTileMap(1000, 1000)
+	layer := &tm.Map.Layers[0]
+	screen := image.Rect(0, 0, 1280, 720)
+	style := rootLayerStyle()
+	var view ebiten.GeoM
+	view.Translate(-8000, -8000)
+
+	b.Run("unculled-vertices", func(b *testing.B) {
+		b.ReportAllocs()
+		tiles := 0
+		for i := 0; i < b.N; i++ {
+			tiles = buildTileVertices(tmm, tm, layer, layer.tileBounds(), view, style)
+		}
+		b.ReportMetric(float64(tiles), "tiles/op")
+	})
+	b.Run("culled-vertices", func(b *testing.B) {
+		b.ReportAllocs()
+		tiles := 0
+		for i := 0; i < b.N; i++ {
+			visible := tm.visibleLayerTiles(layer, view, screen, 1)
+			tiles = buildTileVertices(tmm, tm, layer, visible, view, style)
+		}
+		b.ReportMetric(float64(tiles), "tiles/op")
+	})
+	b.Run("culled-chunk-lookup", func(b *testing.B) {
+		visible := tm.visibleLayerTiles(layer, view, screen, 1)
+		for cy := floorDiv(visible.Min.Y, renderChunkSize); cy*renderChunkSize < visible.Max.Y; cy++ {
+			for cx := floorDiv(visible.Min.X, renderChunkSize); cx*renderChunkSize < visible.Max.X; cx++ {
+				tm.renderChunk(layer, cx, cy)
+			}
+		}
+		b.ReportAllocs()
+		b.ResetTimer()
+		chunks := 0
+		for i := 0; i < b.N; i++ {
+			chunks = 0
+			visible := tm.visibleLayerTiles(layer, view, screen, 1)
+			for cy := floorDiv(visible.Min.Y, renderChunkSize); cy*renderChunkSize < visible.Max.Y; cy++ {
+				for cx := floorDiv(visible.Min.X, renderChunkSize); cx*renderChunkSize < visible.Max.X; cx++ {
+					if chunk := tm.renderChunk(layer, cx, cy); chunk.image != nil {
+						chunks++
+					}
+				}
+			}
+		}
+		b.ReportMetric(float64(chunks), "chunks/op")
+	})
+}
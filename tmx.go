@@ -0,0 +1,726 @@
+package gobonsai
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+type tmxProperty struct {
+	Name         string         `xml:"name,attr"`
+	Type         string         `xml:"type,attr"`
+	PropertyType string         `xml:"propertytype,attr"`
+	Value        string         `xml:"value,attr"`
+	Text         string         `xml:",chardata"`
+	Members      *tmxProperties `xml:"properties"`
+}
+
+type tmxProperties struct {
+	Properties []tmxProperty `xml:"property"`
+}
+
+type tmxImage struct {
+	Source string `xml:"source,attr"`
+	Width  int    `xml:"width,attr"`
+	Height int    `xml:"height,attr"`
+}
+
+type tmxFrame struct {
+	TileID   int `xml:"tileid,attr"`
+	Duration int `xml:"duration,attr"`
+}
+
+type tmxTile struct {
+	ID         int           `xml:"id,attr"`
+	Type       string        `xml:"type,attr"`
+	Class      string        `xml:"class,attr"`
+	Properties tmxProperties `xml:"properties"`
+	Animation  []tmxFrame    `xml:"animation>frame"`
+	Objects    *tmxLayer     `xml:"objectgroup"`
+	Terrain    string        `xml:"terrain,attr"`
+	Image      tmxImage      `xml:"image"`
+	X          int           `xml:"x,attr"`
+	Y          int           `xml:"y,attr"`
+	Width      int           `xml:"width,attr"`
+	Height     int           `xml:"height,attr"`
+}
+
+type tmxTileset struct {
+	FirstGID   int    `xml:"firstgid,attr"`
+	Source     string `xml:"source,attr"`
+	Name       string `xml:"name,attr"`
+	TileWidth  int    `xml:"tilewidth,attr"`
+	TileHeight int    `xml:"tileheight,attr"`
+	Margin     int    `xml:"margin,attr"`
+	Spacing    int    `xml:"spacing,attr"`
+	Columns    int    `xml:"columns,attr"`
+	TileCount  int    `xml:"tilecount,attr"`
+	TileOffset struct {
+		X int `xml:"x,attr"`
+		Y int `xml:"y,attr"`
+	} `xml:"tileoffset"`
+	Image      tmxImage      `xml:"image"`
+	Tiles      []tmxTile     `xml:"tile"`
+	Properties tmxProperties `xml:"properties"`
+	WangSets   []tmxWangSet  `xml:"wangsets>wangset"`
+	Terrains   []Terrain     `xml:"terraintypes>terrain"`
+}
+
+type tmxWangSet struct {
+	Name   string         `xml:"name,attr"`
+	Type   string         `xml:"type,attr"`
+	Tile   int            `xml:"tile,attr"`
+	Colors []tmxWangColor `xml:"wangcolor"`
+	Tiles  []tmxWangTile  `xml:"wangtile"`
+}
+
+type tmxWangColor struct {
+	Name        string   `xml:"name,attr"`
+	Color       string   `xml:"color,attr"`
+	Tile        int      `xml:"tile,attr"`
+	Probability *float64 `xml:"probability,attr"`
+}
+
+type tmxWangTile struct {
+	TileID int    `xml:"tileid,attr"`
+	WangID string `xml:"wangid,attr"`
+}
+
+type tmxTileGID struct {
+	GID uint32 `xml:"gid,attr"`
+}
+
+type tmxChunk struct {
+	X      int          `xml:"x,attr"`
+	Y      int          `xml:"y,attr"`
+	Width  int          `xml:"width,attr"`
+	Height int          `xml:"height,attr"`
+	Tiles  []tmxTileGID `xml:"tile"`
+	Text   string       `xml:",chardata"`
+}
+
+type tmxData struct {
+	Encoding    string       `xml:"encoding,attr"`
+	Compression string       `xml:"compression,attr"`
+	Tiles       []tmxTileGID `xml:"tile"`
+	Chunks      []tmxChunk   `xml:"chunk"`
+	Text        string       `xml:",chardata"`
+}
+
+type tmxPolygon struct {
+	Points string `xml:"points,attr"`
+}
+
+type tmxObject struct {
+	ID         int           `xml:"id,attr"`
+	Name       string        `xml:"name,attr"`
+	Type       string        `xml:"type,attr"`
+	Class      string        `xml:"class,attr"`
+	X          float64       `xml:"x,attr"`
+	Y          float64       `xml:"y,attr"`
+	Width      float64       `xml:"width,attr"`
+	Height     float64       `xml:"height,attr"`
+	Rotation   float64       `xml:"rotation,attr"`
+	GID        uint32        `xml:"gid,attr"`
+	Template   string        `xml:"template,attr"`
+	Properties tmxProperties `xml:"properties"`
+	Ellipse    *struct{}     `xml:"ellipse"`
+	Point      *struct{}     `xml:"point"`
+	Polygon    *tmxPolygon   `xml:"polygon"`
+	Visible    *int          `xml:"visible,attr"`
+}
+
+type tmxLayer struct {
+	Name       string        `xml:"name,attr"`
+	Class      string        `xml:"class,attr"`
+	Width      int           `xml:"width,attr"`
+	Height     int           `xml:"height,attr"`
+	Visible    *int          `xml:"visible,attr"`
+	Opacity    *float64      `xml:"opacity,attr"`
+	OffsetX    float64       `xml:"offsetx,attr"`
+	OffsetY    float64       `xml:"offsety,attr"`
+	ParallaxX  *float64      `xml:"parallaxx,attr"`
+	ParallaxY  *float64      `xml:"parallaxy,attr"`
+	TintColor  string        `xml:"tintcolor,attr"`
+	RepeatX    int           `xml:"repeatx,attr"`
+	RepeatY    int           `xml:"repeaty,attr"`
+	Image      tmxImage      `xml:"image"`
+	Properties tmxProperties `xml:"properties"`
+	Data       tmxData       `xml:"data"`
+	Objects    []tmxObject   `xml:"object"`
+	Layers     tmxLayers     `xml:"-"`
+}
+
+type tmxLayers []Layer
+
+func (tl *tmxLayers) decodeChild(d *xml.Decoder, start xml.StartElement) error {
+	layerTypes := map[string]string{
+		"layer":       "tilelayer",
+		"objectgroup": "objectgroup",
+		"group":       "group",
+		"imagelayer":  "imagelayer",
+	}
+	typ, ok := layerTypes[start.Name.Local]
+	if !ok {
+		return d.Skip()
+	}
+	var raw tmxLayer
+	if typ == "group" {
+		if err := decodeTMXGroup(d, start, &raw); err != nil {
+			return err
+		}
+	} else if err := d.DecodeElement(&raw, &start); err != nil {
+		return err
+	}
+	layer, err := raw.toLayer(typ)
+	if err != nil {
+		return err
+	}
+	*tl = append(*tl, layer)
+	return nil
+}
+
+func decodeTMXGroup(d *xml.Decoder, start xml.StartElement, raw *tmxLayer) error {
+	for _, attr := range start.Attr {
+		switch attr.Name.Local {
+		case "name":
+			raw.Name = attr.Value
+		case "class":
+			raw.Class = attr.Value
+		case "visible":
+			v, _ := strconv.Atoi(attr.Value)
+			raw.Visible = &v
+		case "opacity":
+			v, _ := strconv.ParseFloat(attr.Value, 64)
+			raw.Opacity = &v
+		case "offsetx":
+			raw.OffsetX, _ = strconv.ParseFloat(attr.Value, 64)
+		case "offsety":
+			raw.OffsetY, _ = strconv.ParseFloat(attr.Value, 64)
+		case "parallaxx":
+			v, _ := strconv.ParseFloat(attr.Value, 64)
+			raw.ParallaxX = &v
+		case "parallaxy":
+			v, _ := strconv.ParseFloat(attr.Value, 64)
+			raw.ParallaxY = &v
+		case "tintcolor":
+			raw.TintColor = attr.Value
+		}
+	}
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if t.Name.Local == "properties" {
+				if err := d.DecodeElement(&raw.Properties, &t); err != nil {
+					return err
+				}
+			} else if err := raw.Layers.decodeChild(d, t); err != nil {
+				return err
+			}
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+func (raw tmxLayer) toLayer(typ string) (Layer, error) {
+	layer := Layer{
+		Name:      raw.Name,
+		Type:      typ,
+		Class:     raw.Class,
+		Width:     raw.Width,
+		Height:    raw.Height,
+		Visible:   raw.Visible == nil || *raw.Visible != 0,
+		Opacity:   1,
+		OffsetX:   raw.OffsetX,
+		OffsetY:   raw.OffsetY,
+		ParallaxX: 1,
+		ParallaxY: 1,
+		TintColor: raw.TintColor,
+		Image:     raw.Image.Source,
+		RepeatX:   raw.RepeatX != 0,
+		RepeatY:   raw.RepeatY != 0,
+		RawProps:  raw.Properties.toProperties(),
+		Layers:    raw.Layers,
+	}
+	if raw.Opacity != nil {
+		layer.Opacity = *raw.Opacity
+	}
+	if raw.ParallaxX != nil {
+		layer.ParallaxX = *raw.ParallaxX
+	}
+	if raw.ParallaxY != nil {
+		layer.ParallaxY = *raw.ParallaxY
+	}
+	if typ == "tilelayer" && len(raw.Data.Chunks) > 0 {
+		for i, c := range raw.Data.Chunks {
+			data, err := raw.Data.decode(c.Tiles, c.Text)
+			if err != nil {
+				return Layer{}, fmt.Errorf("layer %s chunk %d,%d: %w", raw.Name, c.X, c.Y, err)
+			}
+			layer.Chunks = append(layer.Chunks, Chunk{X: c.X, Y: c.Y, Width: c.Width, Height: c.Height, Data: data})
+			if i == 0 || c.X < layer.StartX {
+				layer.StartX = c.X
+			}
+			if i == 0 || c.Y < layer.StartY {
+				layer.StartY = c.Y
+			}
+		}
+	} else if typ == "tilelayer" {
+		data, err := raw.Data.decode(raw.Data.Tiles, raw.Data.Text)
+		if err != nil {
+			return Layer{}, fmt.Errorf("layer %s: %w", raw.Name, err)
+		}
+		layer.Data = data
+	}
+	for _, o := range raw.Objects {
+		layer.Objects = append(layer.Objects, o.toObject())
+	}
+	return layer, nil
+}
+
+func (o tmxObject) toObject() Object {
+	obj := Object{
+		ID:       o.ID,
+		Name:     o.Name,
+		Type:     o.Type,
+		X:        o.X,
+		Y:        o.Y,
+		Width:    o.Width,
+		Height:   o.Height,
+		RawProps: o.Properties.toProperties(),
+		GID:      int(o.GID),
+		Rotation: o.Rotation,
+		Ellipse:  o.Ellipse != nil,
+		Point:    o.Point != nil,
+		Template: o.Template,
+		Visible:  o.Visible == nil || *o.Visible != 0,
+	}
+	if obj.Type == "" {
+		obj.Type = o.Class
+	}
+	if o.Polygon != nil {
+		obj.Polygon = parseTMXPoints(o.Polygon.Points)
+	}
+	return obj
+}
+
+func (td tmxData) decode(tiles []tmxTileGID, text string) ([]int, error) {
+	if td.Encoding == "" {
+		data := make([]int, len(tiles))
+		for i, t := range tiles {
+			data[i] = int(t.GID)
+		}
+		return data, nil
+	}
+	return decodeLayerData(td.Encoding, td.Compression, text)
+}
+
+func decodeLayerData(encoding, compression, text string) ([]int, error) {
+	switch encoding {
+	case "csv":
+		var data []int
+		for _, field := range strings.Split(text, ",") {
+			field = strings.TrimSpace(field)
+			if field == "" {
+				continue
+			}
+			gid, err := strconv.ParseUint(field, 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("invalid csv tile data: %w", err)
+			}
+			data = append(data, int(gid))
+		}
+		return data, nil
+	case "base64":
+		raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(text))
+		if err != nil {
+			return nil, fmt.Errorf("invalid base64 tile data: %w", err)
+		}
+		raw, err = decompressLayerData(compression, raw)
+		if err != nil {
+			return nil, err
+		}
+		if len(raw)%4 != 0 {
+			return nil, fmt.Errorf("invalid tile data length: %d", len(raw))
+		}
+		data := make([]int, len(raw)/4)
+		for i := range data {
+			data[i] = int(binary.LittleEndian.Uint32(raw[i*4:]))
+		}
+		return data, nil
+	default:
+		return nil, fmt.Errorf("unsupported tile data encoding: %s", encoding)
+	}
+}
+
+func decompressLayerData(compression string, raw []byte) ([]byte, error) {
+	var r io.Reader
+	switch compression {
+	case "":
+		return raw, nil
+	case "zlib":
+		zr, err := zlib.NewReader(bytes.NewReader(raw))
+		if err != nil {
+			return nil, fmt.Errorf("invalid zlib tile data: %w", err)
+		}
+		defer zr.Close()
+		r = zr
+	case "gzip":
+		gr, err := gzip.NewReader(bytes.NewReader(raw))
+		if err != nil {
+			return nil, fmt.Errorf("invalid gzip tile data: %w", err)
+		}
+		defer gr.Close()
+		r = gr
+	case "zstd":
+		zr, err := zstd.NewReader(bytes.NewReader(raw))
+		if err != nil {
+			return nil, fmt.Errorf("invalid zstd tile data: %w", err)
+		}
+		defer zr.Close()
+		r = zr
+	default:
+		return nil, fmt.Errorf("unsupported tile data compression: %s", compression)
+	}
+	out, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress tile data: %w", err)
+	}
+	return out, nil
+}
+
+func parseTMXPoints(s string) []Vector {
+	var points []Vector
+	for _, pair := range strings.Fields(s) {
+		xy := strings.SplitN(pair, ",", 2)
+		if len(xy) != 2 {
+			continue
+		}
+		x, _ := strconv.ParseFloat(xy[0], 64)
+		y, _ := strconv.ParseFloat(xy[1], 64)
+		points = append(points, Vector{x, y})
+	}
+	return points
+}
+
+func (tp tmxProperties) toProperties() []Property {
+	props := make([]Property, 0, len(tp.Properties))
+	for _, p := range tp.Properties {
+		value := p.Value
+		if value == "" {
+			value = p.Text
+		}
+		prop := Property{Name: p.Name, Type: p.Type, PropertyType: p.PropertyType, Value: value}
+		switch p.Type {
+		case "int", "float", "object":
+			if f, err := strconv.ParseFloat(value, 64); err == nil {
+				prop.Value = f
+			}
+		case "bool":
+			prop.Value = value == "true"
+		case "class":
+			prop.Value = Properties{}
+			if p.Members != nil {
+				prop.Value = NewProperties(p.Members.toProperties())
+			}
+		}
+		props = append(props, prop)
+	}
+	return props
+}
+
+func (ts tmxTileset) toTileset() (Tileset, error) {
+	tileset := Tileset{
+		FirstGID:    ts.FirstGID,
+		Name:        ts.Name,
+		Image:       ts.Image.Source,
+		TileWidth:   ts.TileWidth,
+		TileHeight:  ts.TileHeight,
+		ImageWidth:  ts.Image.Width,
+		ImageHeight: ts.Image.Height,
+		Margin:      ts.Margin,
+		Spacing:     ts.Spacing,
+		Columns:     ts.Columns,
+		TileCount:   ts.TileCount,
+		TileOffset:  TileOffset{X: ts.TileOffset.X, Y: ts.TileOffset.Y},
+		RawProps:    ts.Properties.toProperties(),
+		Terrains:    ts.Terrains,
+	}
+	for _, ws := range ts.WangSets {
+		tileset.WangSets = append(tileset.WangSets, ws.toWangSet())
+	}
+	for _, t := range ts.Tiles {
+		tile := TilesetTile{
+			ID:          t.ID,
+			Type:        t.Type,
+			Properties:  t.Properties.toProperties(),
+			Image:       t.Image.Source,
+			ImageWidth:  t.Image.Width,
+			ImageHeight: t.Image.Height,
+			X:           t.X,
+			Y:           t.Y,
+			Width:       t.Width,
+			Height:      t.Height,
+		}
+		if tile.Type == "" {
+			tile.Type = t.Class
+		}
+		if t.Terrain != "" {
+			for _, v := range strings.Split(t.Terrain, ",") {
+				id, err := strconv.Atoi(strings.TrimSpace(v))
+				if err != nil {
+					id = -1
+				}
+				tile.Terrain = append(tile.Terrain, id)
+			}
+		}
+		for _, f := range t.Animation {
+			tile.Animation = append(tile.Animation, AnimationFrame{TileID: f.TileID, Duration: f.Duration})
+		}
+		if t.Objects != nil {
+			group, err := t.Objects.toLayer("objectgroup")
+			if err != nil {
+				return tileset, fmt.Errorf("tile %d objectgroup: %w", t.ID, err)
+			}
+			tile.ObjectGroup = &group
+		}
+		tileset.Tiles = append(tileset.Tiles, tile)
+	}
+	return tileset, nil
+}
+
+func (ws tmxWangSet) toWangSet() WangSet {
+	set := WangSet{Name: ws.Name, Type: ws.Type, Tile: ws.Tile}
+	for _, c := range ws.Colors {
+		color := WangColor{Name: c.Name, Color: c.Color, Tile: c.Tile, Probability: 1}
+		if c.Probability != nil {
+			color.Probability = *c.Probability
+		}
+		set.Colors = append(set.Colors, color)
+	}
+	for _, t := range ws.Tiles {
+		tile := WangTile{TileID: t.TileID}
+		for i, v := range strings.Split(t.WangID, ",") {
+			if i < len(tile.WangID) {
+				tile.WangID[i], _ = strconv.Atoi(strings.TrimSpace(v))
+			}
+		}
+		set.WangTiles = append(set.WangTiles, tile)
+	}
+	return set
+}
+
+type tmxMap struct {
+	Orientation   string
+	RenderOrder   string
+	StaggerAxis   string
+	StaggerIndex  string
+	HexSideLength int
+	Infinite      bool
+	Width         int
+	Height        int
+	TileWidth     int
+	TileHeight    int
+	Tilesets      []tmxTileset
+	Layers        tmxLayers
+	Properties    tmxProperties
+
+	ParallaxOriginX float64
+	ParallaxOriginY float64
+}
+
+func (tm *tmxMap) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	for _, attr := range start.Attr {
+		v, _ := strconv.Atoi(attr.Value)
+		switch attr.Name.Local {
+		case "orientation":
+			tm.Orientation = attr.Value
+		case "renderorder":
+			tm.RenderOrder = attr.Value
+		case "staggeraxis":
+			tm.StaggerAxis = attr.Value
+		case "staggerindex":
+			tm.StaggerIndex = attr.Value
+		case "hexsidelength":
+			tm.HexSideLength = v
+		case "width":
+			tm.Width = v
+		case "height":
+			tm.Height = v
+		case "tilewidth":
+			tm.TileWidth = v
+		case "tileheight":
+			tm.TileHeight = v
+		case "infinite":
+			tm.Infinite = v != 0
+		case "parallaxoriginx":
+			tm.ParallaxOriginX, _ = strconv.ParseFloat(attr.Value, 64)
+		case "parallaxoriginy":
+			tm.ParallaxOriginY, _ = strconv.ParseFloat(attr.Value, 64)
+		}
+	}
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if t.Name.Local == "tileset" {
+				var ts tmxTileset
+				if err := d.DecodeElement(&ts, &t); err != nil {
+					return err
+				}
+				tm.Tilesets = append(tm.Tilesets, ts)
+			} else if t.Name.Local == "properties" {
+				if err := d.DecodeElement(&tm.Properties, &t); err != nil {
+					return err
+				}
+			} else if err := tm.Layers.decodeChild(d, t); err != nil {
+				return err
+			}
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+func parseTMX(data []byte) (Map, error) {
+	var tm tmxMap
+	if err := xml.Unmarshal(data, &tm); err != nil {
+		return Map{}, err
+	}
+	m := Map{
+		Width:       tm.Width,
+		Height:      tm.Height,
+		TileWidth:   tm.TileWidth,
+		TileHeight:  tm.TileHeight,
+		Orientation: tm.Orientation,
+		RenderOrder: tm.RenderOrder,
+		Infinite:    tm.Infinite,
+		Layers:      tm.Layers,
+		RawProps:    tm.Properties.toProperties(),
+
+		ParallaxOriginX: tm.ParallaxOriginX,
+		ParallaxOriginY: tm.ParallaxOriginY,
+		StaggerAxis:     tm.StaggerAxis,
+		StaggerIndex:    tm.StaggerIndex,
+		HexSideLength:   tm.HexSideLength,
+	}
+	for _, ts := range tm.Tilesets {
+		tileset, err := ts.toTileset()
+		if err != nil {
+			return m, fmt.Errorf("tileset %s: %w", ts.Name, err)
+		}
+		m.Tilesets = append(m.Tilesets, EmbeddedTileset{
+			FirstGID:    ts.FirstGID,
+			Source:      ts.Source,
+			Name:        tileset.Name,
+			Image:       tileset.Image,
+			TileWidth:   tileset.TileWidth,
+			TileHeight:  tileset.TileHeight,
+			ImageWidth:  tileset.ImageWidth,
+			ImageHeight: tileset.ImageHeight,
+			Margin:      tileset.Margin,
+			Spacing:     tileset.Spacing,
+			Columns:     tileset.Columns,
+			TileCount:   tileset.TileCount,
+			TileOffset:  tileset.TileOffset,
+			Tiles:       tileset.Tiles,
+			RawProps:    tileset.RawProps,
+			WangSets:    tileset.WangSets,
+			Terrains:    tileset.Terrains,
+		})
+	}
+	return m, nil
+}
+
+func parseTSX(data []byte) (Tileset, error) {
+	var ts tmxTileset
+	if err := xml.Unmarshal(data, &ts); err != nil {
+		return Tileset{}, err
+	}
+	return ts.toTileset()
+}
+
+func (o *Object) UnmarshalJSON(data []byte) error {
+	type objectAlias Object
+	aux := objectAlias{Visible: true}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	*o = Object(aux)
+	return nil
+}
+
+func (l *Layer) UnmarshalJSON(data []byte) error {
+	type layerAlias Layer
+	aux := struct {
+		*layerAlias
+		Data   json.RawMessage `json:"data"`
+		Chunks []struct {
+			X      int             `json:"x"`
+			Y      int             `json:"y"`
+			Width  int             `json:"width"`
+			Height int             `json:"height"`
+			Data   json.RawMessage `json:"data"`
+		} `json:"chunks"`
+		Encoding    string `json:"encoding"`
+		Compression string `json:"compression"`
+	}{layerAlias: (*layerAlias)(l)}
+	l.ParallaxX, l.ParallaxY = 1, 1
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	decoded, err := decodeJSONLayerData(aux.Data, aux.Encoding, aux.Compression)
+	if err != nil {
+		return fmt.Errorf("layer %s: %w", l.Name, err)
+	}
+	l.Data = decoded
+	l.Chunks = nil
+	for _, c := range aux.Chunks {
+		decoded, err := decodeJSONLayerData(c.Data, aux.Encoding, aux.Compression)
+		if err != nil {
+			return fmt.Errorf("layer %s chunk %d,%d: %w", l.Name, c.X, c.Y, err)
+		}
+		l.Chunks = append(l.Chunks, Chunk{X: c.X, Y: c.Y, Width: c.Width, Height: c.Height, Data: decoded})
+	}
+	return nil
+}
+
+func decodeJSONLayerData(raw json.RawMessage, encoding, compression string) ([]int, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	if encoding == "base64" {
+		var text string
+		if err := json.Unmarshal(raw, &text); err != nil {
+			return nil, err
+		}
+		return decodeLayerData(encoding, compression, text)
+	}
+	var gids []uint32
+	if err := json.Unmarshal(raw, &gids); err != nil {
+		return nil, err
+	}
+	data := make([]int, len(gids))
+	for i, gid := range gids {
+		data[i] = int(gid)
+	}
+	return data, nil
+}
@@ -0,0 +1,273 @@
+package gobonsai
+
+import (
+	"fmt"
+	"image"
+	"math"
+)
+
+type WangColor struct {
+	Name        string  `json:"name"`
+	Color       string  `json:"color"`
+	Tile        int     `json:"tile"`
+	Probability float64 `json:"probability"`
+}
+
+type WangTile struct {
+	TileID int    `json:"tileid"`
+	WangID [8]int `json:"wangid"`
+}
+
+type WangSet struct {
+	Name      string      `json:"name"`
+	Type      string      `json:"type"`
+	Tile      int         `json:"tile"`
+	Colors    []WangColor `json:"colors"`
+	WangTiles []WangTile  `json:"wangtiles"`
+}
+
+type Terrain struct {
+	Name string `json:"name" xml:"name,attr"`
+	Tile int    `json:"tile" xml:"tile,attr"`
+}
+
+type wangEntry struct {
+	gid    int
+	id     [8]int
+	weight float64
+}
+
+type wangIndex struct {
+	set   *WangSet
+	mask  [8]bool
+	tiles []wangEntry
+	byGID map[int][8]int
+}
+
+var wangSides = [8]struct {
+	offset  image.Point
+	indices []int
+}{
+	{image.Pt(0, -1), []int{7, 0, 1}},
+	{image.Pt(1, -1), []int{1}},
+	{image.Pt(1, 0), []int{1, 2, 3}},
+	{image.Pt(1, 1), []int{3}},
+	{image.Pt(0, 1), []int{3, 4, 5}},
+	{image.Pt(-1, 1), []int{5}},
+	{image.Pt(-1, 0), []int{5, 6, 7}},
+	{image.Pt(-1, -1), []int{7}},
+}
+
+func (ts *Tileset) terrainWangSet() WangSet {
+	set := WangSet{Name: "terrains", Type: "corner", Tile: -1}
+	for _, t := range ts.Terrains {
+		set.Colors = append(set.Colors, WangColor{Name: t.Name, Tile: t.Tile, Probability: 1})
+	}
+	for _, tile := range ts.Tiles {
+		if len(tile.Terrain) != 4 {
+			continue
+		}
+		wt := WangTile{TileID: tile.ID}
+		for i, pos := range [4]int{7, 1, 5, 3} {
+			wt.WangID[pos] = max(tile.Terrain[i]+1, 0)
+		}
+		set.WangTiles = append(set.WangTiles, wt)
+	}
+	return set
+}
+
+func (tm *TileMap) indexWangSets() {
+	tm.wangSets = make(map[string]*wangIndex)
+	for _, ts := range tm.Tilesets {
+		sets := ts.WangSets
+		if len(ts.Terrains) > 0 {
+			sets = append(sets[:len(sets):len(sets)], ts.terrainWangSet())
+		}
+		for i := range sets {
+			set := &sets[i]
+			if _, ok := tm.wangSets[set.Name]; ok {
+				continue
+			}
+			idx := &wangIndex{set: set, byGID: make(map[int][8]int)}
+			for p := range idx.mask {
+				switch set.Type {
+				case "corner":
+					idx.mask[p] = p%2 == 1
+				case "edge":
+					idx.mask[p] = p%2 == 0
+				default:
+					idx.mask[p] = true
+				}
+			}
+			for _, wt := range set.WangTiles {
+				entry := wangEntry{gid: ts.FirstGID + wt.TileID, id: wt.WangID, weight: 1}
+				for p, c := range wt.WangID {
+					if idx.mask[p] && c > 0 && c <= len(set.Colors) {
+						entry.weight *= set.Colors[c-1].Probability
+					}
+				}
+				idx.tiles = append(idx.tiles, entry)
+				idx.byGID[entry.gid] = wt.WangID
+			}
+			tm.wangSets[set.Name] = idx
+		}
+	}
+}
+
+func (w *wangIndex) wangIDAt(l *Layer, x, y int) ([8]int, bool) {
+	gid, _ := DecodeGID(l.gidAt(x, y))
+	if gid == 0 {
+		return [8]int{}, true
+	}
+	id, ok := w.byGID[gid]
+	return id, ok
+}
+
+func (w *wangIndex) match(want [8]int, x, y int) int {
+	empty := true
+	for p, c := range want {
+		if w.mask[p] && c != 0 {
+			empty = false
+		}
+	}
+	if empty {
+		return 0
+	}
+	best := math.MaxInt
+	var candidates []wangEntry
+	total := 0.0
+	for _, entry := range w.tiles {
+		score := 0
+		for p := range want {
+			if w.mask[p] && entry.id[p] != want[p] {
+				score++
+			}
+		}
+		switch {
+		case score < best:
+			best, candidates, total = score, []wangEntry{entry}, entry.weight
+		case score == best:
+			candidates = append(candidates, entry)
+			total += entry.weight
+		}
+	}
+	if len(candidates) == 0 {
+		return 0
+	}
+	h := uint32(x)*73856093 ^ uint32(y)*19349663
+	h ^= h >> 13
+	h *= 0x5bd1e995
+	pick := float64(h%10000) / 10000 * total
+	for _, entry := range candidates {
+		if pick -= entry.weight; pick < 0 {
+			return entry.gid
+		}
+	}
+	return candidates[0].gid
+}
+
+func (tmm *TilemapsManager) GetWangSet(name string) *WangSet {
+	tmm.mu.Lock()
+	defer tmm.mu.Unlock()
+	tm, ok := tmm.currentMap()
+	if !ok {
+		return nil
+	}
+	if idx, ok := tm.wangSets[name]; ok {
+		return idx.set
+	}
+	return nil
+}
+
+func (tmm *TilemapsManager) GetWangColorAt(layer, set string, x, y int) [8]int {
+	tmm.mu.Lock()
+	defer tmm.mu.Unlock()
+	tm, ok := tmm.currentMap()
+	if !ok {
+		return [8]int{}
+	}
+	idx, l := tm.wangSets[set], tm.findLayer(layer)
+	if idx == nil || l == nil {
+		return [8]int{}
+	}
+	id, _ := idx.wangIDAt(l, x, y)
+	return id
+}
+
+func (tmm *TilemapsManager) SetWangAt(layer, set string, x, y, color int) error {
+	return tmm.FillWang(layer, set, image.Rect(x, y, x+1, y+1), color)
+}
+
+func (tmm *TilemapsManager) FillWang(layer, set string, r image.Rectangle, color int) error {
+	tmm.mu.Lock()
+	defer tmm.mu.Unlock()
+	tm, ok := tmm.currentMap()
+	if !ok {
+		return fmt.Errorf("no tilemap set")
+	}
+	l := tm.findLayer(layer)
+	if l == nil || l.Type != "tilelayer" {
+		return fmt.Errorf("tile layer %s not found", layer)
+	}
+	idx, ok := tm.wangSets[set]
+	if !ok {
+		return fmt.Errorf("wang set %s not found", set)
+	}
+	if color < 0 || color > len(idx.set.Colors) {
+		return fmt.Errorf("wang color %d out of range for set %s", color, set)
+	}
+
+	inside := func(x, y int) bool {
+		return len(l.Chunks) > 0 || (x >= 0 && y >= 0 && x < l.Width && y < l.Height)
+	}
+	wanted := make(map[image.Point][8]int)
+	var order []image.Point
+	get := func(pt image.Point) ([8]int, bool) {
+		if id, ok := wanted[pt]; ok {
+			return id, true
+		}
+		id, ok := idx.wangIDAt(l, pt.X, pt.Y)
+		if ok {
+			order = append(order, pt)
+		}
+		return id, ok
+	}
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			if !inside(x, y) {
+				continue
+			}
+			pt := image.Pt(x, y)
+			id, ok := get(pt)
+			if !ok {
+				id, order = [8]int{}, append(order, pt)
+			}
+			for p := range id {
+				if idx.mask[p] {
+					id[p] = color
+				}
+			}
+			wanted[pt] = id
+			for _, side := range wangSides {
+				n := pt.Sub(side.offset)
+				if n.In(r) || !inside(n.X, n.Y) {
+					continue
+				}
+				nid, ok := get(n)
+				if !ok {
+					continue
+				}
+				for _, p := range side.indices {
+					nid[p] = color
+				}
+				wanted[n] = nid
+			}
+		}
+	}
+
+	edits := make([]tileEdit, 0, len(order))
+	for _, pt := range order {
+		edits = append(edits, tileEdit{x: pt.X, y: pt.Y, gid: idx.match(wanted[pt], pt.X, pt.Y)})
+	}
+	return tmm.setTiles(tm, l, edits)
+}
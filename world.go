@@ -0,0 +1,382 @@
+package gobonsai
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type roomState int
+
+const defaultStreamDistance = 256
+
+const (
+	roomUnloaded roomState = iota
+	roomLoading
+	roomLoaded
+	roomSpawned
+)
+
+type WorldRoom struct {
+	Name     string  `json:"-"`
+	FileName string  `json:"fileName"`
+	X        float64 `json:"x"`
+	Y        float64 `json:"y"`
+	Width    float64 `json:"width"`
+	Height   float64 `json:"height"`
+
+	path   string
+	state  roomState
+	loaded chan struct{}
+	err    error
+}
+
+type WorldPattern struct {
+	Regexp      string  `json:"regexp"`
+	MultiplierX float64 `json:"multiplierX"`
+	MultiplierY float64 `json:"multiplierY"`
+	OffsetX     float64 `json:"offsetX"`
+	OffsetY     float64 `json:"offsetY"`
+}
+
+type World struct {
+	Maps                 []*WorldRoom   `json:"maps"`
+	Patterns             []WorldPattern `json:"patterns"`
+	OnlyShowAdjacentMaps bool           `json:"onlyShowAdjacentMaps"`
+	StreamDistance       float64        `json:"-"`
+
+	rooms map[string]*WorldRoom
+}
+
+func (w *World) GetRoom(name string) *WorldRoom {
+	return w.rooms[name]
+}
+
+func (r *WorldRoom) contains(x, y float64) bool {
+	return x >= r.X && y >= r.Y && x < r.X+r.Width && y < r.Y+r.Height
+}
+
+func (r *WorldRoom) distance(other *WorldRoom) float64 {
+	dx := max(other.X-(r.X+r.Width), r.X-(other.X+other.Width), 0)
+	dy := max(other.Y-(r.Y+r.Height), r.Y-(other.Y+other.Height), 0)
+	return max(dx, dy)
+}
+
+func (tmm *TilemapsManager) AddWorld(path string) error {
+	worldPath := tmm.normalizeJSONPath(path)
+	data, err := tmm.loadFile(worldPath)
+	if err != nil {
+		return fmt.Errorf("failed to load world: %w", err)
+	}
+	var w World
+	if err := json.Unmarshal(data, &w); err != nil {
+		return fmt.Errorf("failed to parse world: %w", err)
+	}
+	baseDir := filepath.Dir(worldPath)
+	for _, p := range w.Patterns {
+		rooms, err := tmm.matchWorldPattern(baseDir, p)
+		if err != nil {
+			return fmt.Errorf("failed to expand world pattern: %w", err)
+		}
+		w.Maps = append(w.Maps, rooms...)
+	}
+	w.StreamDistance = defaultStreamDistance
+	w.rooms = make(map[string]*WorldRoom, len(w.Maps))
+	for _, room := range w.Maps {
+		room.Name = strings.TrimSuffix(filepath.Base(room.FileName), filepath.Ext(room.FileName))
+		room.path = filepath.Join(baseDir, room.FileName)
+		w.rooms[room.Name] = room
+	}
+
+	tmm.mu.Lock()
+	defer tmm.mu.Unlock()
+	tmm.world = &w
+	tmm.logger.Info("World added:", path, len(w.Maps))
+	return nil
+}
+
+func (tmm *TilemapsManager) matchWorldPattern(dir string, p WorldPattern) ([]*WorldRoom, error) {
+	re, err := regexp.Compile(p.Regexp)
+	if err != nil {
+		return nil, err
+	}
+	var rooms []*WorldRoom
+	for _, name := range Membeds.ReadDir(dir) {
+		match := re.FindStringSubmatch(name)
+		if match == nil || len(match) < 3 {
+			continue
+		}
+		x, _ := strconv.Atoi(match[1])
+		y, _ := strconv.Atoi(match[2])
+		rooms = append(rooms, &WorldRoom{
+			FileName: name,
+			X:        float64(x)*p.MultiplierX + p.OffsetX,
+			Y:        float64(y)*p.MultiplierY + p.OffsetY,
+			Width:    p.MultiplierX,
+			Height:   p.MultiplierY,
+		})
+	}
+	return rooms, nil
+}
+
+func (tmm *TilemapsManager) GetWorld() *World {
+	tmm.mu.Lock()
+	defer tmm.mu.Unlock()
+	return tmm.world
+}
+
+func (tmm *TilemapsManager) SetStreamDistance(distance float64) {
+	tmm.mu.Lock()
+	defer tmm.mu.Unlock()
+	if tmm.world != nil {
+		tmm.world.StreamDistance = max(distance, 0)
+	}
+}
+
+func (tmm *TilemapsManager) OnRoomTransition(callback func(from, to string)) {
+	tmm.mu.Lock()
+	defer tmm.mu.Unlock()
+	tmm.transitions = append(tmm.transitions, callback)
+}
+
+func (tmm *TilemapsManager) StartWorld(room string, scale float64) error {
+	Mecs.RemoveEntities()
+	tmm.mu.Lock()
+	w := tmm.world
+	if w == nil {
+		tmm.mu.Unlock()
+		return fmt.Errorf("no world added")
+	}
+	r, ok := w.rooms[room]
+	if !ok {
+		tmm.mu.Unlock()
+		return fmt.Errorf("room %s not found", room)
+	}
+	for _, other := range w.Maps {
+		if other.state == roomSpawned {
+			other.state = roomLoaded
+		}
+	}
+	tmm.scale = scale
+	tmm.streaming = true
+	if r.state == roomUnloaded {
+		tmm.preloadRoom(r)
+	}
+	loaded := r.loaded
+	tmm.mu.Unlock()
+
+	if loaded != nil {
+		<-loaded
+	}
+
+	tmm.mu.Lock()
+	if r.state < roomLoaded {
+		err := r.err
+		tmm.mu.Unlock()
+		if err == nil {
+			return fmt.Errorf("room %s unloaded while loading", room)
+		}
+		return fmt.Errorf("failed to load room %s: %w", room, err)
+	}
+	tmm.currentTileMap = room
+	tmm.mu.Unlock()
+	tmm.UpdateWorld((r.X+r.Width/2)*scale, (r.Y+r.Height/2)*scale)
+	return nil
+}
+
+func (tmm *TilemapsManager) UpdateWorld(focusX, focusY float64) {
+	tmm.mu.Lock()
+	w := tmm.world
+	if !tmm.streaming || tmm.scale == 0 {
+		tmm.mu.Unlock()
+		return
+	}
+	x, y := focusX/tmm.scale, focusY/tmm.scale
+	from := tmm.currentTileMap
+	current := w.rooms[from]
+	if current == nil || !current.contains(x, y) {
+		for _, r := range w.Maps {
+			if r.contains(x, y) && r.state >= roomLoaded {
+				current = r
+				break
+			}
+		}
+	}
+	if current == nil {
+		tmm.mu.Unlock()
+		return
+	}
+	tmm.currentTileMap = current.Name
+
+	for _, r := range w.Maps {
+		d := current.distance(r)
+		switch {
+		case r == current || d <= w.StreamDistance:
+			if r.state == roomUnloaded {
+				tmm.preloadRoom(r)
+			}
+		case d > w.StreamDistance*2+1 && r.state >= roomLoaded:
+			tmm.unloadRoom(r)
+		}
+	}
+	var spawn []*WorldRoom
+	var maps []*TileMap
+	for _, r := range w.Maps {
+		if r.state == roomLoaded {
+			r.state = roomSpawned
+			spawn = append(spawn, r)
+			maps = append(maps, tmm.tileMaps[r.Name])
+		}
+	}
+	scale := tmm.scale
+	transitions := tmm.transitions
+	tmm.mu.Unlock()
+
+	for i, r := range spawn {
+		last := Mecs.LastEntity()
+		tmm.spawnTilemap(maps[i], scale)
+		tmm.tagRoomEntities(r.Name, last)
+		tmm.logger.Debug("Room spawned:", r.Name)
+	}
+
+	if current.Name != from {
+		tmm.logger.Info("Room transition:", from, "->", current.Name)
+		for _, callback := range transitions {
+			callback(from, current.Name)
+		}
+	}
+}
+
+func (tmm *TilemapsManager) PreloadRoom(name string) {
+	tmm.mu.Lock()
+	defer tmm.mu.Unlock()
+	if tmm.world == nil {
+		return
+	}
+	if r, ok := tmm.world.rooms[name]; ok && r.state == roomUnloaded {
+		tmm.preloadRoom(r)
+	}
+}
+
+func (tmm *TilemapsManager) preloadRoom(r *WorldRoom) {
+	r.state = roomLoading
+	loaded := make(chan struct{})
+	r.loaded = loaded
+	go func() {
+		tm, err := tmm.loadTilemap(r.Name, r.path)
+		tmm.mu.Lock()
+		defer tmm.mu.Unlock()
+		defer close(loaded)
+		if r.loaded != loaded || r.state != roomLoading {
+			return
+		}
+		r.err = err
+		if err != nil {
+			r.state = roomUnloaded
+			tmm.logger.Error("failed to preload room: ", r.Name, err)
+			return
+		}
+		tmm.storeRoom(r, tm)
+	}()
+}
+
+func (tmm *TilemapsManager) storeRoom(r *WorldRoom, tm *TileMap) {
+	tm.WorldX, tm.WorldY = r.X, r.Y
+	tmm.tileMaps[r.Name] = tm
+	r.state = roomLoaded
+	tmm.logger.Debug("Room loaded:", r.Name)
+}
+
+func (tmm *TilemapsManager) UnloadRoom(name string) {
+	tmm.mu.Lock()
+	defer tmm.mu.Unlock()
+	if tmm.world == nil {
+		return
+	}
+	if r, ok := tmm.world.rooms[name]; ok && name != tmm.currentTileMap {
+		tmm.unloadRoom(r)
+	}
+}
+
+func (tmm *TilemapsManager) unloadRoom(r *WorldRoom) {
+	if r.state == roomSpawned {
+		for _, e := range Mecs.GetEntitiesWithComponents("room") {
+			if name, _ := e.GetComponent("room").(string); name == r.Name {
+				Mecs.RemoveEntity(e)
+			}
+		}
+	}
+	if tm, ok := tmm.tileMaps[r.Name]; ok {
+		tm.release()
+		delete(tmm.tileMaps, r.Name)
+	}
+	r.state = roomUnloaded
+	tmm.logger.Debug("Room unloaded:", r.Name)
+}
+
+func (tmm *TilemapsManager) tagRoomEntities(room string, since Entity) {
+	for id := since + 1; id <= Mecs.LastEntity(); id++ {
+		if e, ok := Mecs.GetEntityByID(id); ok && e.GetComponent("persistent") == nil {
+			e.AddComponent("room", room)
+		}
+	}
+}
+
+func (tmm *TilemapsManager) SetTilemapVisible(name string, visible bool) {
+	tmm.mu.Lock()
+	defer tmm.mu.Unlock()
+	if tmm.visibleMaps == nil {
+		tmm.visibleMaps = make(map[string]bool)
+	}
+	if visible {
+		tmm.visibleMaps[name] = true
+	} else {
+		delete(tmm.visibleMaps, name)
+	}
+}
+
+func (tmm *TilemapsManager) activeMaps() []*TileMap {
+	var maps []*TileMap
+	if !tmm.streaming {
+		if tm, ok := tmm.tileMaps[tmm.currentTileMap]; ok {
+			maps = append(maps, tm)
+		}
+	} else {
+		current := tmm.world.rooms[tmm.currentTileMap]
+		for _, r := range tmm.world.Maps {
+			if r.state != roomSpawned {
+				continue
+			}
+			if tmm.world.OnlyShowAdjacentMaps && current != nil && r != current && current.distance(r) > 0 {
+				continue
+			}
+			maps = append(maps, tmm.tileMaps[r.Name])
+		}
+	}
+	names := make([]string, 0, len(tmm.visibleMaps))
+	for name := range tmm.visibleMaps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if tm, ok := tmm.tileMaps[name]; ok && !slices.Contains(maps, tm) {
+			maps = append(maps, tm)
+		}
+	}
+	return maps
+}
+
+func (tm *TileMap) release() {
+	tm.invalidateRenderCache()
+	for _, img := range tm.TilesetImages {
+		img.Deallocate()
+	}
+	for _, img := range tm.layerImages {
+		img.Deallocate()
+	}
+}